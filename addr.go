@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	sapi "static-power/api"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// classifyAddr tell the scope and transport of an advertised address,
+// dns names are resolved to decide the scope
+func classifyAddr(ctx context.Context, maddr multiaddr.Multiaddr) (scope string, transport string) {
+	transport = addrTransport(maddr)
+
+	if madns.Matches(maddr) {
+		resolved, err := madns.Resolve(ctx, maddr)
+		if err != nil || len(resolved) == 0 {
+			return sapi.ScopeUnresolvable, transport
+		}
+		maddr = resolved[0]
+	}
+
+	switch {
+	case manet.IsIPLoopback(maddr):
+		scope = sapi.ScopeLoopback
+	case manet.IsPublicAddr(maddr):
+		scope = sapi.ScopePublic
+	default:
+		// private ranges, and unroutable one like 0.0.0.0 which no one could dial from outside either
+		scope = sapi.ScopePrivate
+	}
+	return scope, transport
+}
+
+func addrTransport(maddr multiaddr.Multiaddr) string {
+	has := func(code int) bool {
+		_, err := maddr.ValueForProtocol(code)
+		return err == nil
+	}
+
+	switch {
+	case has(multiaddr.P_WEBTRANSPORT):
+		return sapi.TransportWebTransport
+	case has(multiaddr.P_WS) || has(multiaddr.P_WSS):
+		return sapi.TransportWS
+	case has(multiaddr.P_QUIC) || has(multiaddr.P_QUIC_V1):
+		return sapi.TransportQUIC
+	case has(multiaddr.P_TCP):
+		return sapi.TransportTCP
+	default:
+		return sapi.TransportOther
+	}
+}
+
+// probeAddrs dial every advertised address of miner on its own, so we know which of them actually work
+func probeAddrs(ctx context.Context, miner *MinerInfo) ([]sapi.AddrProbe, error) {
+	info := miner.Peer
+	if info == nil || info.PeerId == "" || info.Multiaddrs == nil || len(*info.Multiaddrs) == 0 {
+		return nil, fmt.Errorf("no peer info")
+	}
+
+	peerId, err := peer.Decode(info.PeerId)
+	if err != nil {
+		return nil, fmt.Errorf("decode peer id %s: %w", info.PeerId, err)
+	}
+
+	ret := make([]sapi.AddrProbe, 0, len(*info.Multiaddrs))
+	for _, addr := range *info.Multiaddrs {
+		probe := sapi.AddrProbe{
+			MinerID: miner.ID,
+			Addr:    addr,
+		}
+
+		maddr, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			probe.Scope = sapi.ScopeUnresolvable
+			probe.Transport = sapi.TransportOther
+			probe.Error = fmt.Sprintf("parsing multiaddr %s: %s", addr, err)
+			ret = append(ret, probe)
+			continue
+		}
+		probe.Scope, probe.Transport = classifyAddr(ctx, maddr)
+
+		probe.Latency, err = dialAddr(ctx, peer.AddrInfo{ID: peerId, Addrs: []multiaddr.Multiaddr{maddr}})
+		if err != nil {
+			probe.Error = err.Error()
+		} else {
+			probe.Reachable = true
+		}
+		ret = append(ret, probe)
+	}
+	return ret, nil
+}
+
+// dialAddr connect to peer with a fresh host, so dial backoff and addresses learned
+// from previous dials don't affect the result, return the latency in milliseconds
+func dialAddr(ctx context.Context, addrInfo peer.AddrInfo) (int64, error) {
+	host, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		return 0, err
+	}
+	defer host.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err = host.Connect(ctx, addrInfo)
+	latency := time.Since(start).Milliseconds()
+	if err != nil {
+		return latency, fmt.Errorf("connecting to peer %s: %w", addrInfo.ID, err)
+	}
+	return latency, nil
+}
+
+func getAddrProbes(miners []sapi.Miner) map[*MinerInfo][]sapi.AddrProbe {
+	ret := make(map[*MinerInfo][]sapi.AddrProbe)
+	var lk sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(len(miners))
+	throttle := make(chan struct{}, 1000)
+	for i := range miners {
+		miner := &miners[i]
+		throttle <- struct{}{}
+
+		go func(miner *MinerInfo) {
+			defer func() {
+				wg.Done()
+				<-throttle
+			}()
+
+			probes, err := probeAddrs(context.Background(), miner)
+			if err != nil {
+				log.Printf("probe addrs for miner %s: %s", miner.ID.String(), err)
+				return
+			}
+
+			lk.Lock()
+			ret[miner] = probes
+			lk.Unlock()
+		}(miner)
+	}
+	wg.Wait()
+	return ret
+}
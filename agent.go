@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	sapi "static-power/api"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"github.com/urfave/cli/v2"
+)
+
+var updateAgentCmd = &cli.Command{
+	Name: "update-agent",
+	Flags: append([]cli.Flag{
+		&cli.BoolFlag{
+			Name:  "each-addr",
+			Usage: "also dial every advertised address on its own to check reachability and latency",
+		},
+	}, append(recorderFlags, outputFlags...)...),
+	Action: func(c *cli.Context) error {
+		r, err := openRecorder(c, c.String("network"))
+		if err != nil {
+			return err
+		}
+		defer closeRecorder(r)
+
+		miners, err := r.GetMiners()
+		if err != nil {
+			return fmt.Errorf("get miners : %w", err)
+		}
+
+		probes := getAgentInfo(miners)
+
+		log.Printf("update (%d) probe result of (%d), ", len(probes), len(miners))
+		for _, probe := range probes {
+			probe := probe
+			err := r.Transaction(func(r recorder) error {
+				return postAgentProbe(r, probe)
+			})
+			if err != nil {
+				log.Printf("update probe records for(%d) : %s", probe.Result.MinerID, err)
+			}
+		}
+
+		if c.Bool("each-addr") {
+			addrProbes := getAddrProbes(miners)
+			log.Printf("update addr probes of (%d) miners", len(addrProbes))
+			for miner, probes := range addrProbes {
+				err := r.UpdateAddrProbes(probes)
+				if err != nil {
+					log.Printf("update addr probes for(%d) : %s", miner.ID, err)
+				}
+			}
+		}
+		// get miner get agent
+		return nil
+	},
+}
+
+// postAgentProbe write the records of probing one miner
+func postAgentProbe(r recorder, probe *agentProbe) error {
+	err := r.UpdateProbeResult(probe.Result)
+	if err != nil {
+		return fmt.Errorf("update probe result: %w", err)
+	}
+
+	if probe.Identify != nil {
+		err := r.UpdateIdentifyInfo(probe.Identify)
+		if err != nil {
+			return fmt.Errorf("update identify info: %w", err)
+		}
+	}
+
+	if probe.Agent != nil {
+		err := r.UpdateAgentInfo(probe.Agent)
+		if err != nil {
+			return fmt.Errorf("update agent info: %w", err)
+		}
+		log.Printf("update agent info for(%d) success , Name(%s)", probe.Agent.MinerID, probe.Agent.Name)
+	}
+	return nil
+}
+
+// give up a peer which doesn't finish connect and identify in time
+const probeTimeout = 30 * time.Second
+
+// agentProbe is the outcome of probing the peer of one miner, Agent is nil when the probe failed
+type agentProbe struct {
+	Agent    *sapi.AgentInfo
+	Identify *sapi.IdentifyInfo
+	Result   *sapi.ProbeResult
+}
+
+// getAgentInfo probe every unique peer once, and fan the result out to all miners advertising the peer
+func getAgentInfo(miners []sapi.Miner) []*agentProbe {
+	groups := groupByPeer(miners)
+	log.Printf("probe (%d) unique peers of (%d) miners", len(groups), len(miners))
+
+	ret := make([]*agentProbe, 0, len(miners))
+	var wg sync.WaitGroup
+	var lk sync.Mutex
+
+	wg.Add(len(groups))
+	throttle := make(chan struct{}, 5000)
+	for i := range groups {
+		group := groups[i]
+		throttle <- struct{}{}
+
+		go func(group []*MinerInfo) {
+			defer func() {
+				wg.Done()
+				<-throttle
+				// manager.TrimOpenConns(ctx)
+			}()
+
+			probe := probeAgent(context.Background(), peerOf(group))
+
+			lk.Lock()
+			defer lk.Unlock()
+			for _, miner := range group {
+				p := probe.forMiner(miner)
+				if p.Result.Outcome != sapi.ProbeSuccess {
+					log.Printf("get agent for miner %s: %s: %s", miner.ID.String(), p.Result.Outcome, p.Result.Error)
+				} else if p.Agent == nil {
+					log.Printf("user agent (%s) of miner %s not change", miner.Agent.Name, miner.ID.String())
+				}
+				ret = append(ret, p)
+			}
+		}(group)
+	}
+	wg.Wait()
+	return ret
+}
+
+// groupByPeer group miners advertising the same peer id, miners without peer id are on their own
+func groupByPeer(miners []sapi.Miner) [][]*MinerInfo {
+	var ret [][]*MinerInfo
+	index := make(map[string]int)
+	for i := range miners {
+		miner := &miners[i]
+		if miner.Peer == nil || miner.Peer.PeerId == "" {
+			ret = append(ret, []*MinerInfo{miner})
+			continue
+		}
+		if idx, ok := index[miner.Peer.PeerId]; ok {
+			ret[idx] = append(ret[idx], miner)
+			continue
+		}
+		index[miner.Peer.PeerId] = len(ret)
+		ret = append(ret, []*MinerInfo{miner})
+	}
+	return ret
+}
+
+// peerOf return a miner to probe on behalf of the group, with multiaddrs of all miners in the group
+func peerOf(group []*MinerInfo) *MinerInfo {
+	if len(group) == 1 || group[0].Peer == nil {
+		return group[0]
+	}
+
+	addrs := sapi.Multiaddrs{}
+	seen := make(map[string]struct{})
+	for _, miner := range group {
+		if miner.Peer.Multiaddrs == nil {
+			continue
+		}
+		for _, addr := range *miner.Peer.Multiaddrs {
+			if _, ok := seen[addr]; !ok {
+				seen[addr] = struct{}{}
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+
+	peer := *group[0].Peer
+	peer.Multiaddrs = &addrs
+	return &MinerInfo{
+		ID:   group[0].ID,
+		Peer: &peer,
+	}
+}
+
+// forMiner copy the probe for another miner sharing the same peer,
+// Agent is dropped if it doesn't change from the latest one of miner
+func (p *agentProbe) forMiner(miner *MinerInfo) *agentProbe {
+	result := *p.Result
+	result.MinerID = miner.ID
+	ret := &agentProbe{
+		Result: &result,
+	}
+
+	if p.Identify != nil {
+		identify := *p.Identify
+		identify.MinerID = miner.ID
+		ret.Identify = &identify
+	}
+
+	if p.Agent != nil && (miner.Agent == nil || miner.Agent.Name != p.Agent.Name) {
+		ret.Agent = &sapi.AgentInfo{
+			MinerID: miner.ID,
+			Name:    p.Agent.Name,
+		}
+	}
+	return ret
+}
+
+// probeAgent connect to the peer of miner and read its agent and identify info,
+// every failure is categorized into the probe result instead of returned
+func probeAgent(ctx context.Context, miner *MinerInfo) *agentProbe {
+	ret := &agentProbe{
+		Result: &sapi.ProbeResult{
+			MinerID: miner.ID,
+		},
+	}
+	fail := func(outcome string, err error) *agentProbe {
+		ret.Result.Outcome = outcome
+		ret.Result.Error = err.Error()
+		return ret
+	}
+
+	info := miner.Peer
+	if info == nil || info.PeerId == "" || info.Multiaddrs == nil || len(*info.Multiaddrs) == 0 {
+		return fail(sapi.ProbeNoPeerInfo, fmt.Errorf("no peer info"))
+	}
+
+	peerId, err := peer.Decode(info.PeerId)
+	if err != nil {
+		return fail(sapi.ProbeDecodeError, fmt.Errorf("decode peer id %s: %w", info.PeerId, err))
+	}
+
+	addrInfo := peer.AddrInfo{
+		ID:    peerId,
+		Addrs: []multiaddr.Multiaddr{},
+	}
+
+	for _, addr := range *info.Multiaddrs {
+		maddr, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return fail(sapi.ProbeDecodeError, fmt.Errorf("parsing multiaddr %s: %w", addr, err))
+		}
+		addrInfo.Addrs = append(addrInfo.Addrs, maddr)
+	}
+
+	host, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		return fail(sapi.ProbeDialError, err)
+	}
+	defer host.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	// Connect returns after the identify exchange, so the peerstore is filled from here
+	start := time.Now()
+	err = host.Connect(ctx, addrInfo)
+	ret.Result.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		return fail(dialOutcome(err), fmt.Errorf("connecting to peer %s: %w", addrInfo.ID, err))
+	}
+
+	if conns := host.Network().ConnsToPeer(addrInfo.ID); len(conns) > 0 {
+		ret.Result.Addr = conns[0].RemoteMultiaddr().String()
+	}
+
+	identify, err := getIdentifyInfo(host, miner.ID, addrInfo.ID)
+	if err != nil {
+		log.Printf("get identify info for miner %s: %s", miner.ID.String(), err)
+	} else {
+		ret.Identify = identify
+	}
+
+	userAgentI, err := host.Peerstore().Get(addrInfo.ID, "AgentVersion")
+	if err != nil {
+		return fail(sapi.ProbeNoAgent, fmt.Errorf("getting user agent for peer %s: %w", addrInfo.ID, err))
+	}
+
+	userAgent, ok := userAgentI.(string)
+	if !ok {
+		return fail(sapi.ProbeNoAgent, fmt.Errorf("user agent for peer %s was not a string", addrInfo.ID))
+	}
+
+	if userAgent == "" {
+		return fail(sapi.ProbeNoAgent, fmt.Errorf("user agent empty"))
+	}
+
+	ret.Result.Outcome = sapi.ProbeSuccess
+	ret.Agent = &sapi.AgentInfo{
+		MinerID: miner.ID,
+		Name:    userAgent,
+	}
+	return ret
+}
+
+// dialOutcome categorize the error returned by connecting to a peer
+func dialOutcome(err error) string {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || strings.Contains(msg, "i/o timeout") || strings.Contains(msg, "deadline exceeded"):
+		return sapi.ProbeTimeout
+	case strings.Contains(msg, "connection refused"):
+		return sapi.ProbeRefused
+	default:
+		return sapi.ProbeDialError
+	}
+}
+
+// getIdentifyInfo read the result of identify exchange with peer from the peerstore of h
+func getIdentifyInfo(h host.Host, miner abi.ActorID, id peer.ID) (*sapi.IdentifyInfo, error) {
+	protocols, err := h.Peerstore().GetProtocols(id)
+	if err != nil {
+		return nil, fmt.Errorf("getting protocols for peer %s: %w", id, err)
+	}
+
+	protocolVersion := ""
+	pv, err := h.Peerstore().Get(id, "ProtocolVersion")
+	if err == nil {
+		protocolVersion, _ = pv.(string)
+	}
+
+	ps := sapi.Protocols{}
+	for _, p := range protocols {
+		ps = append(ps, string(p))
+	}
+	sort.Strings(ps)
+
+	addrs := sapi.Multiaddrs{}
+	for _, addr := range h.Peerstore().Addrs(id) {
+		addrs = append(addrs, addr.String())
+	}
+
+	return &sapi.IdentifyInfo{
+		MinerID:         miner,
+		PeerId:          id.String(),
+		ProtocolVersion: protocolVersion,
+		Protocols:       &ps,
+		ListenAddrs:     &addrs,
+	}, nil
+}
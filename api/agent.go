@@ -0,0 +1,175 @@
+package api
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/mod/semver"
+)
+
+// AgentVersion is the structured form of an agent string like
+// `lotus-1.23.2+mainnet+git.abc` or `venus-market/v2.8.0`
+type AgentVersion struct {
+	// the software family, like lotus, boost, venus or droplet
+	Impl string
+	// the part of software name after impl, like market in venus-market
+	Component string
+	// semantic version without the leading v
+	Version string
+	// the network the software built for, like mainnet or calibnet
+	BuildNetwork string
+	GitCommit    string
+}
+
+// ParseAgent split agent string into software, version and build metadata,
+// fields which could not be recognized are left empty
+func ParseAgent(name string) AgentVersion {
+	var ret AgentVersion
+
+	parts := strings.Split(strings.TrimSpace(name), "+")
+	software, version := splitVersion(parts[0])
+
+	software = strings.ToLower(software)
+	if idx := strings.Index(software, "-"); idx >= 0 {
+		ret.Impl = software[:idx]
+		ret.Component = software[idx+1:]
+	} else {
+		ret.Impl = software
+	}
+	ret.Version = strings.TrimPrefix(version, "v")
+
+	for _, meta := range parts[1:] {
+		if strings.HasPrefix(meta, "git") {
+			ret.GitCommit = strings.TrimPrefix(strings.TrimPrefix(meta, "git"), ".")
+		} else if ret.BuildNetwork == "" {
+			ret.BuildNetwork = meta
+		}
+	}
+	return ret
+}
+
+// splitVersion split `name/version` or `name-version`, where version starts with a digit or v and a digit
+func splitVersion(s string) (string, string) {
+	if idx := strings.Index(s, "/"); idx >= 0 {
+		return s[:idx], s[idx+1:]
+	}
+
+	isVersion := func(v string) bool {
+		v = strings.TrimPrefix(v, "v")
+		return len(v) > 0 && unicode.IsDigit(rune(v[0]))
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '-' && isVersion(s[i+1:]) {
+			return s[:i], s[i+1:]
+		}
+	}
+	return s, ""
+}
+
+// parse fill the structured fields of agent from its name
+func (agent *AgentInfo) parse() {
+	v := ParseAgent(agent.Name)
+	agent.Impl = v.Impl
+	agent.Component = v.Component
+	agent.Version = v.Version
+	agent.BuildNetwork = v.BuildNetwork
+	agent.GitCommit = v.GitCommit
+}
+
+// backfillAgentVersions parse the agents recorded before the structured fields exist
+func (a *Api) backfillAgentVersions() error {
+	var names []string
+	err := a.db.Model(&AgentInfo{}).Where("impl = ? or impl is null", "").Distinct().Pluck("name", &names).Error
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		agent := AgentInfo{Name: name}
+		agent.parse()
+		if agent.Impl == "" {
+			continue
+		}
+		err := a.db.Model(&AgentInfo{}).Where("name = ?", name).Where("impl = ? or impl is null", "").Updates(map[string]interface{}{
+			"impl":          agent.Impl,
+			"component":     agent.Component,
+			"version":       agent.Version,
+			"build_network": agent.BuildNetwork,
+			"git_commit":    agent.GitCommit,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type VersionStaticInfo struct {
+	Impl      string
+	Component string
+	Version   string
+	StaticInfo
+}
+
+// GetVersionStatic count miners and their power by the software version of their latest agent
+func (a *Api) GetVersionStatic() ([]*VersionStaticInfo, error) {
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		impl, component, version string
+	}
+	statics := make(map[key]*VersionStaticInfo)
+	for _, miner := range miners {
+		if miner.Agent == nil || miner.Power == nil {
+			continue
+		}
+		k := key{miner.Agent.Impl, miner.Agent.Component, miner.Agent.Version}
+		s, ok := statics[k]
+		if !ok {
+			s = &VersionStaticInfo{
+				Impl:      k.impl,
+				Component: k.component,
+				Version:   k.version,
+			}
+			statics[k] = s
+		}
+		s.add(miner)
+	}
+
+	ret := make([]*VersionStaticInfo, 0, len(statics))
+	for _, s := range statics {
+		ret = append(ret, s)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Impl != ret[j].Impl {
+			return ret[i].Impl < ret[j].Impl
+		}
+		if ret[i].Component != ret[j].Component {
+			return ret[i].Component < ret[j].Component
+		}
+		return newerVersion(ret[i].Version, ret[j].Version)
+	})
+	return ret, nil
+}
+
+// newerVersion tell if version a is above b by semantic version, versions not parsed are put below
+// those parsed and ordered as strings
+func newerVersion(a, b string) bool {
+	va, vb := "v"+strings.TrimPrefix(a, "v"), "v"+strings.TrimPrefix(b, "v")
+	okA, okB := semver.IsValid(va), semver.IsValid(vb)
+	switch {
+	case okA && okB:
+		if c := semver.Compare(va, vb); c != 0 {
+			return c > 0
+		}
+		return a > b
+	case okA != okB:
+		return okA
+	default:
+		return a > b
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/test-go/testify/require"
+)
+
+func TestParseAgent(t *testing.T) {
+	cases := map[string]AgentVersion{
+		"lotus-1.23.2+mainnet+git.abc": {
+			Impl:         "lotus",
+			Version:      "1.23.2",
+			BuildNetwork: "mainnet",
+			GitCommit:    "abc",
+		},
+		"venus-market/v2.8.0": {
+			Impl:      "venus",
+			Component: "market",
+			Version:   "2.8.0",
+		},
+		"boost-1.7.4-rc1+git.a6ae2d8": {
+			Impl:      "boost",
+			Version:   "1.7.4-rc1",
+			GitCommit: "a6ae2d8",
+		},
+		"droplet-v2.9.0+calibnet": {
+			Impl:         "droplet",
+			Version:      "2.9.0",
+			BuildNetwork: "calibnet",
+		},
+		"venus-sealer": {
+			Impl:      "venus",
+			Component: "sealer",
+		},
+		"": {},
+	}
+
+	for name, expect := range cases {
+		require.Equal(t, expect, ParseAgent(name), name)
+	}
+}
+
+func TestVersionStatic(t *testing.T) {
+	db := newDB(t)
+
+	// agent recorded before the structured fields exist
+	err := db.AutoMigrate(&Miner{}, &AgentInfo{})
+	require.NoError(t, err)
+	err = db.Create(&AgentInfo{MinerID: abi.ActorID(1003), Name: "lotus-1.23.2+mainnet+git.abc"}).Error
+	require.NoError(t, err)
+	err = db.Create(&Miner{ID: abi.ActorID(1003)}).Error
+	require.NoError(t, err)
+
+	api := NewApi(db)
+
+	agents := []AgentInfo{
+		{MinerID: abi.ActorID(1001), Name: "venus-market/v2.8.0"},
+		{MinerID: abi.ActorID(1002), Name: "venus-market/v2.7.0"},
+		{MinerID: abi.ActorID(1002), Name: "venus-market/v2.8.0"},
+	}
+	for _, agent := range agents {
+		err := api.UpdateMinerAgentInfo(&agent)
+		require.NoError(t, err)
+	}
+
+	powers := []PowerInfo{
+		{MinerID: abi.ActorID(1001), RawBytePower: pib(1), QualityAdjPower: pib(1)},
+		{MinerID: abi.ActorID(1002), RawBytePower: pib(2), QualityAdjPower: pib(2)},
+		{MinerID: abi.ActorID(1003), RawBytePower: pib(3), QualityAdjPower: pib(3)},
+	}
+	for _, power := range powers {
+		err := api.UpdateMinerPowerInfo(&power)
+		require.NoError(t, err)
+	}
+
+	res, err := api.GetVersionStatic()
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	require.Equal(t, "lotus", res[0].Impl)
+	require.Equal(t, "1.23.2", res[0].Version)
+	require.Equal(t, 1, res[0].Count)
+	require.Equal(t, 3.0, res[0].QAP)
+
+	require.Equal(t, "venus", res[1].Impl)
+	require.Equal(t, "market", res[1].Component)
+	require.Equal(t, "2.8.0", res[1].Version)
+	require.Equal(t, 2, res[1].Count)
+	require.Equal(t, 3.0, res[1].QAP)
+}
+
+func TestNewerVersion(t *testing.T) {
+	versions := []string{"1.9.0", "unknown", "1.23.2", "2.10.0", "v2.8.0", "1.23.2-rc1", "dev"}
+	sort.Slice(versions, func(i, j int) bool {
+		return newerVersion(versions[i], versions[j])
+	})
+	require.Equal(t, []string{"2.10.0", "v2.8.0", "1.23.2", "1.23.2-rc1", "1.9.0", "unknown", "dev"}, versions)
+}
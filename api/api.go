@@ -2,8 +2,10 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/filecoin-project/go-state-types/abi"
 	"gorm.io/gorm"
@@ -12,22 +14,33 @@ import (
 // could not be zero, because zero represent nil in gorm
 var NetWork abi.ActorID = 1
 
-var db *gorm.DB = nil
-
 func NewApi(d *gorm.DB) *Api {
-	d.AutoMigrate(&Miner{}, &PeerInfo{}, &PowerInfo{}, &AgentInfo{})
-	db = d
-	return &Api{}
+	d.AutoMigrate(&Miner{}, &MinerNetwork{}, &PeerInfo{}, &PowerInfo{}, &AgentInfo{}, &IdentifyInfo{}, &ProbeResult{}, &AddrProbe{}, &GeoInfo{}, &MinerMeta{}, &SectorPower{}, &FaultInfo{}, &BalanceInfo{}, &SectorExpiration{}, &BlockInfo{}, &Checkpoint{})
+	a := &Api{db: d, network: DefaultNetwork}
+	if err := a.backfillAgentVersions(); err != nil {
+		log.Printf("backfill agent versions: %s", err)
+	}
+	if err := a.backfillNetwork(); err != nil {
+		log.Printf("backfill network: %s", err)
+	}
+	return a
+}
+
+// Transaction run fn with an Api whose writes are committed together, or rolled back if fn returns an error
+func (a *Api) Transaction(fn func(tx *Api) error) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&Api{db: tx, network: a.network, belowMin: a.belowMin})
+	})
 }
 
 func (a *Api) getMiner(id abi.ActorID) (*Miner, error) {
 	var miner Miner
-	err := db.First(&miner, "id = ?", id).Error
+	err := a.db.Where("id in (?)", a.minerIDs()).First(&miner, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
 	var peer PeerInfo
-	err = db.Order("updated_at desc").First(&peer, "miner_id = ?", miner.ID).Error
+	err = a.scope().Order("updated_at desc").First(&peer, "miner_id = ?", miner.ID).Error
 	if err == nil {
 		miner.Peer = &peer
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -35,26 +48,85 @@ func (a *Api) getMiner(id abi.ActorID) (*Miner, error) {
 	}
 
 	var power PowerInfo
-	err = db.Order("updated_at desc").First(&power, "miner_id = ?", miner.ID).Error
+	err = a.scope().Order("updated_at desc").First(&power, "miner_id = ?", miner.ID).Error
 	if err == nil {
-		miner.Power = &power
+		// miners not selected are kept without power, so statistics skip them
+		if a.selected(&power) {
+			miner.Power = &power
+		}
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
 
 	var agent AgentInfo
-	err = db.Order("updated_at desc").First(&agent, "miner_id = ?", miner.ID).Error
+	err = a.scope().Order("updated_at desc").First(&agent, "miner_id = ?", miner.ID).Error
 	if err == nil {
 		miner.Agent = &agent
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, err
 	}
+
+	var identify IdentifyInfo
+	err = a.scope().Order("updated_at desc").First(&identify, "miner_id = ?", miner.ID).Error
+	if err == nil {
+		miner.Identify = &identify
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var probe ProbeResult
+	err = a.scope().Order("updated_at desc").First(&probe, "miner_id = ?", miner.ID).Error
+	if err == nil {
+		miner.Probe = &probe
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var geo GeoInfo
+	err = a.scope().Order("updated_at desc").First(&geo, "miner_id = ?", miner.ID).Error
+	if err == nil {
+		miner.Geo = &geo
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var meta MinerMeta
+	err = a.scope().Order("updated_at desc").First(&meta, "miner_id = ?", miner.ID).Error
+	if err == nil {
+		miner.Meta = &meta
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var sectors SectorPower
+	err = a.scope().Order("updated_at desc").First(&sectors, "miner_id = ?", miner.ID).Error
+	if err == nil {
+		miner.Sectors = &sectors
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var faults FaultInfo
+	err = a.scope().Order("updated_at desc").First(&faults, "miner_id = ?", miner.ID).Error
+	if err == nil {
+		miner.Faults = &faults
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var balance BalanceInfo
+	err = a.scope().Order("updated_at desc").First(&balance, "miner_id = ?", miner.ID).Error
+	if err == nil {
+		miner.Balance = &balance
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
 	return &miner, nil
 }
 
 func (a *Api) getOnePower(miner abi.ActorID) (*PowerInfo, error) {
 	var power PowerInfo
-	err := db.Order("updated_at desc").First(&power, "miner_id = ?", miner).Error
+	err := a.scope().Order("updated_at desc").First(&power, "miner_id = ?", miner).Error
 	if err != nil {
 		return nil, err
 	}
@@ -66,20 +138,27 @@ func (a *Api) getPowers(ids ...abi.ActorID) ([]PowerInfo, error) {
 
 	var powers []PowerInfo
 	// 获取所有 miner_id in (ids) 的最新的 power 信息
-	err := db.Select("miner_id, raw_byte_power ,quality_adj_power, updated_at,  max(updated_at) as max_updated_at").Where("miner_id in ?", ids).Group("miner_id").Table("power_infos").Find(&powers).Error
+	err := a.scope().Select("miner_id, raw_byte_power ,quality_adj_power, below_min, updated_at,  max(updated_at) as max_updated_at").Where("miner_id in ?", ids).Group("miner_id").Table("power_infos").Find(&powers).Error
 
-	// err := db.Joins("inner join (?) as subquery on power_infos.miner_id = subquery.miner_id and power_infos.updated_at = subquery.updated_at", subquery).Find(&powers, "miner_id in ?", ids).Error
+	// err := a.db.Joins("inner join (?) as subquery on power_infos.miner_id = subquery.miner_id and power_infos.updated_at = subquery.updated_at", subquery).Find(&powers, "miner_id in ?", ids).Error
 	if err != nil {
 		return nil, err
 	}
-	return powers, nil
+
+	ret := powers[:0]
+	for i := range powers {
+		if a.selected(&powers[i]) {
+			ret = append(ret, powers[i])
+		}
+	}
+	return ret, nil
 }
 
 func (a *Api) getAgents(ids ...abi.ActorID) ([]AgentInfo, error) {
 	ids = unique(ids)
 
 	var agents []AgentInfo
-	err := db.Select("miner_id, name, updated_at,  max(updated_at) as max_updated_at").Where("miner_id in ?", ids).Group("miner_id").Table("agent_infos").Find(&agents).Error
+	err := a.scope().Select("miner_id, name, impl, component, version, build_network, git_commit, updated_at,  max(updated_at) as max_updated_at").Where("miner_id in ?", ids).Group("miner_id").Table("agent_infos").Find(&agents).Error
 	if err != nil {
 		return nil, err
 	}
@@ -102,31 +181,39 @@ func (a *Api) getMiners(ids ...abi.ActorID) ([]Miner, error) {
 
 func (a *Api) GetVenusStatic() (*StaticInfo, error) {
 	venus_agent := []AgentInfo{}
-	db.Where("name like ?", "%venus%").Or("name like ?", "%droplet%").Or("name like ?", "%market%").Find(&venus_agent)
+	a.scope().Where("(name like ? or name like ? or name like ?)", "%venus%", "%droplet%", "%market%").Find(&venus_agent)
 	venus_power, err := a.getPowers(sliceMap(venus_agent, func(a AgentInfo) abi.ActorID { return a.MinerID })...)
 	if err != nil {
 		return nil, err
 	}
-	return staticByPower(venus_power, false), nil
+	sectors, err := a.getSectorPowers(sliceMap(venus_power, func(p PowerInfo) abi.ActorID { return p.MinerID })...)
+	if err != nil {
+		return nil, err
+	}
+	return staticByPower(venus_power, sectors, false), nil
 }
 
 func (a *Api) GetLotusStatic() (*StaticInfo, error) {
 	lotus_agent := []AgentInfo{}
-	db.Where("name like ?", "%lotus%").Or("name like ?", "%boost%").Find(&lotus_agent)
+	a.scope().Where("(name like ? or name like ?)", "%lotus%", "%boost%").Find(&lotus_agent)
 	lotus_power, err := a.getPowers(sliceMap(lotus_agent, func(a AgentInfo) abi.ActorID { return a.MinerID })...)
 	if err != nil {
 		return nil, err
 	}
-	return staticByPower(lotus_power, false), nil
+	sectors, err := a.getSectorPowers(sliceMap(lotus_power, func(p PowerInfo) abi.ActorID { return p.MinerID })...)
+	if err != nil {
+		return nil, err
+	}
+	return staticByPower(lotus_power, sectors, false), nil
 }
 
 func (a *Api) GetProportion() (float64, error) {
 	venus_agent := []AgentInfo{}
 	lotus_agent := []AgentInfo{}
 	// name contains venus or droplet or market
-	db.Where("name like ?", "%venus%").Or("name like ?", "%droplet%").Or("name like ?", "%market%").Find(&venus_agent)
+	a.scope().Where("(name like ? or name like ? or name like ?)", "%venus%", "%droplet%", "%market%").Find(&venus_agent)
 	// name contains lotus or boost
-	db.Where("name like ?", "%lotus%").Or("name like ?", "%boost%").Find(&lotus_agent)
+	a.scope().Where("(name like ? or name like ?)", "%lotus%", "%boost%").Find(&lotus_agent)
 
 	// venus_power := []PowerInfo{}
 	// lotus_power := []PowerInfo{}
@@ -141,8 +228,9 @@ func (a *Api) GetProportion() (float64, error) {
 		return 0.0, err
 	}
 
-	venus_static := staticByPower(venus_power, false)
-	lotus_static := staticByPower(lotus_power, false)
+	// proportion only cares about QAP, which doesn't depend on measured sectors
+	venus_static := staticByPower(venus_power, nil, false)
+	lotus_static := staticByPower(lotus_power, nil, false)
 
 	if venus_static.QAP == 0 {
 		return 0.0, nil
@@ -151,20 +239,27 @@ func (a *Api) GetProportion() (float64, error) {
 	return venus_static.QAP / (venus_static.QAP + lotus_static.QAP), nil
 }
 
+// minerIDs is the subquery of miners seen on the network of Api
+func (a *Api) minerIDs() *gorm.DB {
+	return a.scope().Model(&MinerNetwork{}).Select("miner_id")
+}
+
 // get miner info to query agent
 func (a *Api) GetAllMiners() ([]Miner, error) {
 	var miners []Miner
-	db.Find(&miners)
+	a.db.Where("id in (?)", a.minerIDs()).Find(&miners)
 	return a.getMiners(sliceMap(miners, func(m Miner) abi.ActorID { return m.ID })...)
 }
 
 // update miner Agent
 func (a *Api) UpdateMinerAgentInfo(agent *AgentInfo) error {
-	err := db.Save(&Miner{ID: agent.MinerID}).Error
+	agent.parse()
+	a.stamp(&agent.Network)
+	err := a.saveMiner(agent.MinerID, agent.Network)
 	if err != nil {
 		return err
 	}
-	err = db.Create(agent).Error
+	err = a.db.Create(agent).Error
 	if err != nil {
 		return err
 	}
@@ -173,11 +268,12 @@ func (a *Api) UpdateMinerAgentInfo(agent *AgentInfo) error {
 
 // update Miner PeerInfo
 func (a *Api) UpdateMinerPeerInfo(peer *PeerInfo) error {
-	err := db.Save(&Miner{ID: peer.MinerID}).Error
+	a.stamp(&peer.Network)
+	err := a.saveMiner(peer.MinerID, peer.Network)
 	if err != nil {
 		return err
 	}
-	err = db.Create(peer).Error
+	err = a.db.Create(peer).Error
 	if err != nil {
 		return err
 	}
@@ -186,17 +282,192 @@ func (a *Api) UpdateMinerPeerInfo(peer *PeerInfo) error {
 
 // update Miner PowerInfo
 func (a *Api) UpdateMinerPowerInfo(power *PowerInfo) error {
-	err := db.Save(&Miner{ID: power.MinerID}).Error
+	a.stamp(&power.Network)
+	err := a.saveMiner(power.MinerID, power.Network)
+	if err != nil {
+		return err
+	}
+	err = a.db.Create(power).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// update Miner IdentifyInfo
+func (a *Api) UpdateMinerIdentifyInfo(identify *IdentifyInfo) error {
+	a.stamp(&identify.Network)
+	err := a.saveMiner(identify.MinerID, identify.Network)
 	if err != nil {
 		return err
 	}
-	err = db.Create(power).Error
+	err = a.db.Create(identify).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// update Miner ProbeResult
+func (a *Api) UpdateMinerProbeResult(probe *ProbeResult) error {
+	a.stamp(&probe.Network)
+	err := a.saveMiner(probe.MinerID, probe.Network)
+	if err != nil {
+		return err
+	}
+	err = a.db.Create(probe).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type ReachabilityInfo struct {
+	// miners have been probed at least once
+	Probed    int
+	Reachable int
+	// count of miners by the outcome of their latest probe
+	Outcomes map[string]int
+	// average latency of reachable miners in milliseconds
+	AvgLatency float64
+}
+
+// GetReachabilityStatic summarize the latest probe of every miner, grouped by implementation
+func (a *Api) GetReachabilityStatic() (map[string]*ReachabilityInfo, error) {
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]*ReachabilityInfo)
+	for _, miner := range miners {
+		if miner.Probe == nil {
+			continue
+		}
+		impl := implementation(miner.Agent)
+		r, ok := ret[impl]
+		if !ok {
+			r = &ReachabilityInfo{Outcomes: make(map[string]int)}
+			ret[impl] = r
+		}
+		r.Probed++
+		r.Outcomes[miner.Probe.Outcome]++
+		if miner.Probe.Outcome == ProbeSuccess {
+			r.AvgLatency = (r.AvgLatency*float64(r.Reachable) + float64(miner.Probe.Latency)) / float64(r.Reachable+1)
+			r.Reachable++
+		}
+	}
+	return ret, nil
+}
+
+// update the probes of every advertised address of one miner
+func (a *Api) UpdateMinerAddrProbes(probes []AddrProbe) error {
+	if len(probes) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range probes {
+		if probes[i].MinerID != probes[0].MinerID {
+			return fmt.Errorf("probes belong to different miners: %d, %d", probes[0].MinerID, probes[i].MinerID)
+		}
+		a.stamp(&probes[i].Network)
+		if probes[i].Network != probes[0].Network {
+			return fmt.Errorf("probes belong to different networks: %s, %s", probes[0].Network, probes[i].Network)
+		}
+		probes[i].UpdatedAt = now
+	}
+
+	err := a.saveMiner(probes[0].MinerID, probes[0].Network)
+	if err != nil {
+		return err
+	}
+	return a.db.Create(&probes).Error
+}
+
+// get the address probes of the latest round of every miner
+func (a *Api) getLatestAddrProbes() ([]AddrProbe, error) {
+	var probes []AddrProbe
+	latest := a.scope().Model(&AddrProbe{}).Select("miner_id, max(updated_at)").Group("miner_id")
+	err := a.scope().Where("(miner_id, updated_at) in (?)", latest).Find(&probes).Error
+	if err != nil {
+		return nil, err
+	}
+	return probes, nil
+}
+
+type AddrStaticInfo struct {
+	// miners have at least one address probed
+	Miners int
+	// miners reachable over at least one address
+	Reachable int
+	// count of addresses by scope and by transport
+	Scopes     map[string]int
+	Transports map[string]int
+	// count of miners reachable over each transport
+	ReachableTransports map[string]int
+}
+
+// GetAddrStatic summarize the latest address probes of all miners
+func (a *Api) GetAddrStatic() (*AddrStaticInfo, error) {
+	probes, err := a.getLatestAddrProbes()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &AddrStaticInfo{
+		Scopes:              make(map[string]int),
+		Transports:          make(map[string]int),
+		ReachableTransports: make(map[string]int),
+	}
+
+	reachable := make(map[abi.ActorID]map[string]struct{})
+	for _, p := range probes {
+		ret.Scopes[p.Scope]++
+		ret.Transports[p.Transport]++
+
+		if _, ok := reachable[p.MinerID]; !ok {
+			ret.Miners++
+			reachable[p.MinerID] = make(map[string]struct{})
+		}
+		if p.Reachable {
+			reachable[p.MinerID][p.Transport] = struct{}{}
+		}
+	}
+
+	for _, transports := range reachable {
+		if len(transports) > 0 {
+			ret.Reachable++
+		}
+		for t := range transports {
+			ret.ReachableTransports[t]++
+		}
+	}
+	return ret, nil
+}
+
+// GetProtocolStatic count miners supporting each protocol, grouped by implementation
+func (a *Api) GetProtocolStatic() (map[string]map[string]int, error) {
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]map[string]int)
+	for _, miner := range miners {
+		if miner.Identify == nil || miner.Identify.Protocols == nil {
+			continue
+		}
+		impl := implementation(miner.Agent)
+		if ret[impl] == nil {
+			ret[impl] = make(map[string]int)
+		}
+		for _, p := range unique(*miner.Identify.Protocols) {
+			ret[impl][p]++
+		}
+	}
+	return ret, nil
+}
+
 const PiB float64 = 1024 * 1024 * 1024 * 1024 * 1024
 
 type StaticInfo struct {
@@ -208,60 +479,88 @@ type StaticInfo struct {
 	DCP float64
 	// Raw Power of CCP sector
 	CCP float64
+	// Raw Power of regular deals, which is part of CCP, only known for measured miners
+	DealP float64
+	// count of miners whose DCP is measured from their sectors rather than estimated
+	Measured int
 }
 
-func staticByPower(powers []PowerInfo, excludeCcOnly bool) *StaticInfo {
-	ret := StaticInfo{
-		Count: len(powers),
+// minerPower is the power of one miner in PiB
+type minerPower struct {
+	RBP, QAP, DCP, CCP, DealP float64
+	Measured                  bool
+}
+
+// powerOf convert power into PiB, DC power is taken from the sectors measured by deep crawl if there is,
+// otherwise estimated from the 10x multiplier of verified deals
+func powerOf(power PowerInfo, sectors *SectorPower) minerPower {
+	var ret minerPower
+	ret.RBP = float64(power.RawBytePower.Uint64()) / PiB
+	ret.QAP = float64(power.QualityAdjPower.Uint64()) / PiB
+
+	if sectors != nil && sectors.VerifiedBytes != nil && sectors.DealBytes != nil {
+		ret.Measured = true
+		ret.DCP = float64(sectors.VerifiedBytes.Uint64()) / PiB
+		ret.DealP = float64(sectors.DealBytes.Uint64()) / PiB
+	} else {
+		ret.DCP = (ret.QAP - ret.RBP) / 9
 	}
-	for _, p := range powers {
-		RBP := float64(p.RawBytePower.Uint64()) / PiB
-		QAP := float64(p.QualityAdjPower.Uint64()) / PiB
+	ret.CCP = ret.RBP - ret.DCP
+	return ret
+}
 
-		DCP := (QAP - RBP) / 9
-		CCP := RBP - DCP
+// add the power of one miner into the static
+func (s *StaticInfo) add(miner Miner) {
+	if miner.Power == nil {
+		return
+	}
+	s.addPower(powerOf(*miner.Power, miner.Sectors))
+}
+
+func (s *StaticInfo) addPower(p minerPower) {
+	s.Count++
+	s.RBP += p.RBP
+	s.QAP += p.QAP
+	s.DCP += p.DCP
+	s.CCP += p.CCP
+	s.DealP += p.DealP
+	if p.Measured {
+		s.Measured++
+	}
+}
 
-		ccOnly := CCP > 0.0000000001 && DCP < 0.0000000001
+func staticByPower(powers []PowerInfo, sectors map[abi.ActorID]*SectorPower, excludeCcOnly bool) *StaticInfo {
+	ret := StaticInfo{}
+	for _, p := range powers {
+		mp := powerOf(p, sectors[p.MinerID])
+
+		ccOnly := mp.CCP > 0.0000000001 && mp.DCP < 0.0000000001
 		if excludeCcOnly && ccOnly {
 			log.Printf("miner(%d) has no DC power", p.MinerID)
 			continue
 		}
 
-		ret.RBP += RBP
-		ret.QAP += QAP
-		ret.DCP += DCP
-		ret.CCP += CCP
+		ret.addPower(mp)
 	}
 	return &ret
 }
 
 func static(miners []Miner, excludeCcOnly bool) *StaticInfo {
-	ret := StaticInfo{
-		Count: len(miners),
-	}
+	ret := StaticInfo{}
 	for _, miner := range miners {
 		if miner.Power == nil {
 			log.Printf("miner(%d) has no power info", miner.ID)
 			continue
 		}
-		power := *miner.Power
-		RBP := float64(power.RawBytePower.Uint64()) / PiB
-		QAP := float64(power.QualityAdjPower.Uint64()) / PiB
+		mp := powerOf(*miner.Power, miner.Sectors)
 
-		DCP := (QAP - RBP) / 9
-		CCP := RBP - DCP
-
-		ccOnly := CCP > 0.0000000001 && DCP < 0.0000000001
+		ccOnly := mp.CCP > 0.0000000001 && mp.DCP < 0.0000000001
 		if excludeCcOnly && ccOnly {
 			log.Printf("miner(%d) has no DC power", miner.ID)
 			continue
 		}
 
-		ret.Count++
-		ret.RBP += RBP
-		ret.QAP += QAP
-		ret.DCP += DCP
-		ret.CCP += CCP
+		ret.addPower(mp)
 	}
 	return &ret
 }
@@ -300,25 +599,14 @@ func processData(miners []Miner) map[string]*StaticInfo {
 			log.Printf("miner(%d) has no agent info", miner.ID)
 			continue
 		}
-		power := *miner.Power
 		agent := *miner.Agent
-		RBP := float64(power.RawBytePower.Uint64()) / PiB
-		QAP := float64(power.QualityAdjPower.Uint64()) / PiB
-
-		DCP := (QAP - RBP) / 9
-		CCP := RBP - DCP
+		mp := powerOf(*miner.Power, miner.Sectors)
 
 		// then Condition  should be great than zero , but consider the influence of float64, so we use small enough value
-		hasDeal := DCP > 0.0000000001
+		hasDeal := mp.DCP > 0.0000000001
 
 		update := func(name string) {
-			a := staticInfo[name]
-			a.Count++
-			a.RBP += RBP
-			a.QAP += QAP
-			a.DCP += DCP
-			a.CCP += CCP
-			staticInfo[name] = a
+			staticInfo[name].addPower(mp)
 		}
 
 		update(All)
@@ -345,6 +633,36 @@ func processData(miners []Miner) map[string]*StaticInfo {
 	return staticInfo
 }
 
+const (
+	ImplVenus   = "venus"
+	ImplLotus   = "lotus"
+	ImplOthers  = "others"
+	ImplUnknown = "unknown"
+)
+
+// ParseImpl check the implementation to select miners of, empty selects all
+func ParseImpl(s string) (string, error) {
+	switch s {
+	case "", ImplVenus, ImplLotus, ImplOthers, ImplUnknown:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown implementation %s, expect venus, lotus, others or unknown", s)
+	}
+}
+
+// implementation classify miner by agent, miner without agent info is unknown
+func implementation(agent *AgentInfo) string {
+	if agent == nil {
+		return ImplUnknown
+	}
+	if isVenus(agent.Name) {
+		return ImplVenus
+	} else if isLotus(agent.Name) {
+		return ImplLotus
+	}
+	return ImplOthers
+}
+
 func isVenus(s string) bool {
 	return strings.Contains(s, "venus") || strings.Contains(s, "droplet")
 }
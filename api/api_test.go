@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	mbig "math/big"
 	"testing"
@@ -84,6 +85,32 @@ func TestApiUpdate(t *testing.T) {
 		peer.UpdatedAt = res[0].Peer.UpdatedAt
 		require.Equal(t, *peer, *res[0].Peer)
 	})
+
+	t.Run("update identify info", func(t *testing.T) {
+		db := newDB(t)
+
+		api := NewApi(db)
+		miner := abi.ActorID(1002)
+
+		p := Protocols{"/fil/retrieval/transports/1.0.0", "/ipfs/id/1.0.0"}
+		m := Multiaddrs{"/ip4/1.2.3.4/tcp/1234"}
+		identify := &IdentifyInfo{
+			MinerID:         miner,
+			PeerId:          "test_peer",
+			ProtocolVersion: "ipfs/0.1.0",
+			Protocols:       &p,
+			ListenAddrs:     &m,
+		}
+
+		err := api.UpdateMinerIdentifyInfo(identify)
+		require.NoError(t, err)
+
+		res, err := api.GetAllMiners()
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		identify.UpdatedAt = res[0].Identify.UpdatedAt
+		require.Equal(t, *identify, *res[0].Identify)
+	})
 }
 
 func TestApiGetInfo(t *testing.T) {
@@ -344,6 +371,155 @@ func TestStatic(t *testing.T) {
 	})
 }
 
+func TestProtocolStatic(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+
+	agents := []AgentInfo{
+		{MinerID: abi.ActorID(1001), Name: "droplet"},
+		{MinerID: abi.ActorID(1002), Name: "boost"},
+	}
+	for _, agent := range agents {
+		err := api.UpdateMinerAgentInfo(&agent)
+		require.NoError(t, err)
+	}
+
+	identifies := []IdentifyInfo{
+		{MinerID: abi.ActorID(1001), Protocols: &Protocols{"/fil/storage/mk/1.1.0"}},
+		{MinerID: abi.ActorID(1002), Protocols: &Protocols{"/fil/storage/mk/1.1.0", "/legs/head/1.0.0"}},
+		{MinerID: abi.ActorID(1003), Protocols: &Protocols{"/fil/storage/mk/1.1.0"}},
+	}
+	for _, identify := range identifies {
+		err := api.UpdateMinerIdentifyInfo(&identify)
+		require.NoError(t, err)
+	}
+
+	res, err := api.GetProtocolStatic()
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string]int{
+		ImplVenus:   {"/fil/storage/mk/1.1.0": 1},
+		ImplLotus:   {"/fil/storage/mk/1.1.0": 1, "/legs/head/1.0.0": 1},
+		ImplUnknown: {"/fil/storage/mk/1.1.0": 1},
+	}, res)
+}
+
+func TestReachabilityStatic(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+
+	err := api.UpdateMinerAgentInfo(&AgentInfo{MinerID: abi.ActorID(1001), Name: "venus"})
+	require.NoError(t, err)
+	err = api.UpdateMinerAgentInfo(&AgentInfo{MinerID: abi.ActorID(1002), Name: "venus"})
+	require.NoError(t, err)
+
+	probes := []ProbeResult{
+		{MinerID: abi.ActorID(1001), Outcome: ProbeTimeout},
+		{MinerID: abi.ActorID(1001), Outcome: ProbeSuccess, Latency: 100},
+		{MinerID: abi.ActorID(1002), Outcome: ProbeSuccess, Latency: 300},
+		{MinerID: abi.ActorID(1003), Outcome: ProbeRefused},
+	}
+	for _, probe := range probes {
+		err := api.UpdateMinerProbeResult(&probe)
+		require.NoError(t, err)
+	}
+
+	res, err := api.GetReachabilityStatic()
+	require.NoError(t, err)
+	require.Equal(t, &ReachabilityInfo{
+		Probed:     2,
+		Reachable:  2,
+		Outcomes:   map[string]int{ProbeSuccess: 2},
+		AvgLatency: 200,
+	}, res[ImplVenus])
+	require.Equal(t, &ReachabilityInfo{
+		Probed:   1,
+		Outcomes: map[string]int{ProbeRefused: 1},
+	}, res[ImplUnknown])
+}
+
+func TestAddrStatic(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+
+	// the first round of 1001 is replaced by the second one
+	rounds := [][]AddrProbe{
+		{
+			{MinerID: abi.ActorID(1001), Addr: "a", Scope: ScopePublic, Transport: TransportTCP},
+		},
+		{
+			{MinerID: abi.ActorID(1001), Addr: "a", Scope: ScopePublic, Transport: TransportTCP, Reachable: true},
+			{MinerID: abi.ActorID(1001), Addr: "b", Scope: ScopePublic, Transport: TransportQUIC, Reachable: true},
+		},
+		{
+			{MinerID: abi.ActorID(1002), Addr: "c", Scope: ScopePrivate, Transport: TransportTCP},
+			{MinerID: abi.ActorID(1002), Addr: "d", Scope: ScopeUnresolvable, Transport: TransportWS},
+		},
+	}
+	for _, probes := range rounds {
+		err := api.UpdateMinerAddrProbes(probes)
+		require.NoError(t, err)
+	}
+
+	err := api.UpdateMinerAddrProbes([]AddrProbe{{MinerID: abi.ActorID(1001)}, {MinerID: abi.ActorID(1002)}})
+	require.Error(t, err)
+
+	res, err := api.GetAddrStatic()
+	require.NoError(t, err)
+	require.Equal(t, &AddrStaticInfo{
+		Miners:              2,
+		Reachable:           1,
+		Scopes:              map[string]int{ScopePublic: 2, ScopePrivate: 1, ScopeUnresolvable: 1},
+		Transports:          map[string]int{TransportTCP: 2, TransportQUIC: 1, TransportWS: 1},
+		ReachableTransports: map[string]int{TransportTCP: 1, TransportQUIC: 1},
+	}, res)
+}
+
+func TestMeasuredStatic(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+
+	for _, agent := range []AgentInfo{
+		{MinerID: abi.ActorID(1001), Name: "venus"},
+		{MinerID: abi.ActorID(1002), Name: "venus"},
+	} {
+		err := api.UpdateMinerAgentInfo(&agent)
+		require.NoError(t, err)
+	}
+
+	// 1001 has 1 PiB verified deal, estimated from QAP
+	// 1002 has 2 PiB verified deal and 1 PiB regular deal, measured from sectors
+	for _, power := range []PowerInfo{
+		{MinerID: abi.ActorID(1001), RawBytePower: pib(4), QualityAdjPower: pib(13)},
+		{MinerID: abi.ActorID(1002), RawBytePower: pib(4), QualityAdjPower: pib(22)},
+	} {
+		err := api.UpdateMinerPowerInfo(&power)
+		require.NoError(t, err)
+	}
+	err := api.UpdateMinerSectorPower(&SectorPower{
+		MinerID:       abi.ActorID(1002),
+		CCBytes:       pib(1),
+		DealBytes:     pib(1),
+		VerifiedBytes: pib(2),
+	})
+	require.NoError(t, err)
+
+	res, err := api.GetVenusStatic()
+	require.NoError(t, err)
+	require.Equal(t, &StaticInfo{
+		Count:    2,
+		RBP:      8,
+		QAP:      35,
+		DCP:      3,
+		CCP:      5,
+		DealP:    1,
+		Measured: 1,
+	}, res)
+}
+
 func TestJasonMarshal(t *testing.T) {
 
 	t.Run("marshal math big", func(t *testing.T) {
@@ -394,3 +570,34 @@ func pib(count int) *Power {
 	p := Power((big.NewInt(int64(float64(count) * PiB))))
 	return &p
 }
+
+func TestTransaction(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+
+	// rolled back as a whole
+	err := api.Transaction(func(tx *Api) error {
+		err := tx.UpdateMinerPowerInfo(&PowerInfo{MinerID: abi.ActorID(1001), RawBytePower: pib(1), QualityAdjPower: pib(1)})
+		require.NoError(t, err)
+		return errors.New("abort")
+	})
+	require.Error(t, err)
+	miners, err := api.GetAllMiners()
+	require.NoError(t, err)
+	require.Len(t, miners, 0)
+
+	err = api.ForNetwork("calibrationnet").Transaction(func(tx *Api) error {
+		err := tx.UpdateMinerPowerInfo(&PowerInfo{MinerID: abi.ActorID(1001), RawBytePower: pib(1), QualityAdjPower: pib(1)})
+		if err != nil {
+			return err
+		}
+		return tx.UpdateMinerMeta(&MinerMeta{MinerID: abi.ActorID(1001), Owner: "f0100"})
+	})
+	require.NoError(t, err)
+	miners, err = api.ForNetwork("calibrationnet").GetAllMiners()
+	require.NoError(t, err)
+	require.Len(t, miners, 1)
+	require.Equal(t, "f0100", miners[0].Meta.Owner)
+	require.Equal(t, "calibrationnet", miners[0].Meta.Network)
+}
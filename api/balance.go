@@ -0,0 +1,72 @@
+package api
+
+// update Miner BalanceInfo
+func (a *Api) UpdateMinerBalance(balance *BalanceInfo) error {
+	a.stamp(&balance.Network)
+	err := a.saveMiner(balance.MinerID, balance.Network)
+	if err != nil {
+		return err
+	}
+	err = a.db.Create(balance).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// BalanceStaticInfo is the power of miners and the funds collateralized by them, funds are in FIL
+type BalanceStaticInfo struct {
+	StaticInfo
+	// miners whose balance is known
+	Reported          int
+	Balance           float64
+	Available         float64
+	InitialPledge     float64
+	LockedFunds       float64
+	PreCommitDeposits float64
+	// InitialPledge, LockedFunds and PreCommitDeposits together
+	Collateral float64
+}
+
+func (s *BalanceStaticInfo) add(miner Miner) {
+	s.StaticInfo.add(miner)
+	if miner.Balance == nil {
+		return
+	}
+
+	b := miner.Balance
+	fil := func(p *Power) float64 {
+		if p == nil || p.Int == nil {
+			return 0
+		}
+		return toFIL(p)
+	}
+	s.Reported++
+	s.Balance += fil(b.Balance)
+	s.Available += fil(b.Available)
+	s.InitialPledge += fil(b.InitialPledge)
+	s.LockedFunds += fil(b.LockedFunds)
+	s.PreCommitDeposits += fil(b.PreCommitDeposits)
+	s.Collateral += fil(b.InitialPledge) + fil(b.LockedFunds) + fil(b.PreCommitDeposits)
+}
+
+// GetBalanceStatic aggregate the funds of miners by implementation, from their latest balance
+func (a *Api) GetBalanceStatic() (map[string]*BalanceStaticInfo, error) {
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]*BalanceStaticInfo)
+	for _, miner := range miners {
+		if miner.ID == NetWork || miner.Power == nil {
+			continue
+		}
+		impl := implementation(miner.Agent)
+		if ret[impl] == nil {
+			ret[impl] = &BalanceStaticInfo{}
+		}
+		ret[impl].add(miner)
+	}
+	return ret, nil
+}
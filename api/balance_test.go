@@ -0,0 +1,50 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/test-go/testify/require"
+)
+
+func TestBalanceStatic(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	fil := func(count int64) *Power {
+		p := Power(big.Mul(big.NewInt(count), big.NewInt(1e18)))
+		return &p
+	}
+
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1001, Name: "venus-market/v2.8.0"}))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1002, Name: "venus-market/v2.8.0"}))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1003, Name: "lotus-1.23.0"}))
+	for _, id := range []abi.ActorID{1001, 1002, 1003} {
+		require.NoError(t, api.UpdateMinerPowerInfo(&PowerInfo{MinerID: id, RawBytePower: pib(1), QualityAdjPower: pib(1)}))
+	}
+	for _, b := range []BalanceInfo{
+		{MinerID: 1001, Balance: fil(100), Available: fil(10), InitialPledge: fil(80), LockedFunds: fil(10), PreCommitDeposits: fil(0), FeeDebt: fil(0)},
+		{MinerID: 1002, Balance: fil(50), Available: fil(5), InitialPledge: fil(40), LockedFunds: fil(4), PreCommitDeposits: fil(1), FeeDebt: fil(0)},
+	} {
+		b := b
+		require.NoError(t, api.UpdateMinerBalance(&b))
+	}
+
+	s, err := api.GetBalanceStatic()
+	require.NoError(t, err)
+
+	venus := s[ImplVenus]
+	require.NotNil(t, venus)
+	require.Equal(t, 2, venus.Count)
+	require.Equal(t, 2, venus.Reported)
+	require.InDelta(t, 150, venus.Balance, 1e-9)
+	require.InDelta(t, 120, venus.InitialPledge, 1e-9)
+	require.InDelta(t, 135, venus.Collateral, 1e-9)
+
+	// counted in power but no balance known
+	lotus := s[ImplLotus]
+	require.NotNil(t, lotus)
+	require.Equal(t, 1, lotus.Count)
+	require.Equal(t, 0, lotus.Reported)
+}
@@ -0,0 +1,50 @@
+package api
+
+import "fmt"
+
+// selectors of miners below the min power, which the network doesn't count the power of
+const (
+	// BelowMinExclude leave miners below min power out of statistics, the default
+	BelowMinExclude = "exclude"
+	// BelowMinInclude count miners below min power together with the others
+	BelowMinInclude = "include"
+	// BelowMinOnly count only miners below min power
+	BelowMinOnly = "only"
+)
+
+// ParseBelowMin check the selector of miners below min power, BelowMinExclude if empty
+func ParseBelowMin(s string) (string, error) {
+	switch s {
+	case "":
+		return BelowMinExclude, nil
+	case BelowMinExclude, BelowMinInclude, BelowMinOnly:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown below min selector %s, expect include, exclude or only", s)
+	}
+}
+
+// WithBelowMin return an Api whose statistics select miners below min power by sel, see BelowMinExclude and the others
+func (a *Api) WithBelowMin(sel string) *Api {
+	sel, err := ParseBelowMin(sel)
+	if err != nil {
+		sel = BelowMinExclude
+	}
+	return &Api{db: a.db, network: a.network, belowMin: sel}
+}
+
+// selected tell whether the power of a miner is selected by the below min selector of Api,
+// the network is always selected as shares are taken of it
+func (a *Api) selected(power *PowerInfo) bool {
+	if power.MinerID == NetWork {
+		return true
+	}
+	switch a.belowMin {
+	case BelowMinInclude:
+		return true
+	case BelowMinOnly:
+		return power.BelowMin
+	default:
+		return !power.BelowMin
+	}
+}
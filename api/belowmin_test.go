@@ -0,0 +1,79 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/test-go/testify/require"
+)
+
+func TestBelowMin(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	for _, p := range []PowerInfo{
+		{MinerID: abi.ActorID(1001), RawBytePower: pib(10), QualityAdjPower: pib(10)},
+		{MinerID: abi.ActorID(1002), RawBytePower: pib(1), QualityAdjPower: pib(1), BelowMin: true},
+		{MinerID: abi.ActorID(1003), RawBytePower: pib(2), QualityAdjPower: pib(2), BelowMin: true},
+	} {
+		p := p
+		require.NoError(t, api.UpdateMinerPowerInfo(&p))
+	}
+	for _, id := range []abi.ActorID{1001, 1002, 1003} {
+		require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: id, Name: "venus-market/v2.8.0"}))
+	}
+
+	for sel, expect := range map[string]int{
+		"":              1,
+		BelowMinExclude: 1,
+		BelowMinInclude: 3,
+		BelowMinOnly:    2,
+	} {
+		s, err := api.WithBelowMin(sel).GetVenusStatic()
+		require.NoError(t, err, sel)
+		require.Equal(t, expect, s.Count, sel)
+
+		versions, err := api.WithBelowMin(sel).GetVersionStatic()
+		require.NoError(t, err, sel)
+		require.Len(t, versions, 1, sel)
+		require.Equal(t, expect, versions[0].Count, sel)
+	}
+
+	// the power of a single miner is shown anyway
+	detail, err := api.GetMinerDetail(1002)
+	require.NoError(t, err)
+	require.NotNil(t, detail.Power)
+	require.True(t, detail.Power.BelowMin)
+
+	_, err = ParseBelowMin("some")
+	require.Error(t, err)
+}
+
+func TestBelowMinOnlyNetwork(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	for _, p := range []PowerInfo{
+		{MinerID: NetWork, RawBytePower: pib(100), QualityAdjPower: pib(100)},
+		{MinerID: abi.ActorID(1001), RawBytePower: pib(10), QualityAdjPower: pib(10)},
+		{MinerID: abi.ActorID(1002), RawBytePower: pib(1), QualityAdjPower: pib(1), BelowMin: true},
+	} {
+		p := p
+		require.NoError(t, api.UpdateMinerPowerInfo(&p))
+	}
+	for _, id := range []abi.ActorID{1001, 1002} {
+		require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: id, Name: "venus-market/v2.8.0"}))
+	}
+
+	// shares of miners below min power are still taken of the network
+	s, err := api.WithBelowMin(BelowMinOnly).GetExpirationStatic(1)
+	require.NoError(t, err)
+	require.Len(t, s, 1)
+	require.InDelta(t, 1, s[0].RemainingQAP, 1e-9)
+	require.InDelta(t, 0.01, s[0].QAPShare, 1e-9)
+
+	blocks, err := api.WithBelowMin(BelowMinOnly).GetBlockStatic(0, 0)
+	require.NoError(t, err)
+	require.Len(t, blocks.Implementations, 1)
+	require.InDelta(t, 0.01, blocks.Implementations[0].QAPShare, 1e-9)
+}
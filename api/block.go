@@ -0,0 +1,162 @@
+package api
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+)
+
+// UpdateBlocks save blocks, blocks recorded already are overwritten, so ranges could be walked again
+func (a *Api) UpdateBlocks(blocks []BlockInfo) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	saved := make(map[abi.ActorID]struct{})
+	for i := range blocks {
+		a.stamp(&blocks[i].Network)
+		if _, ok := saved[blocks[i].MinerID]; ok {
+			continue
+		}
+		err := a.saveMiner(blocks[i].MinerID, blocks[i].Network)
+		if err != nil {
+			return err
+		}
+		saved[blocks[i].MinerID] = struct{}{}
+	}
+	return a.db.Save(&blocks).Error
+}
+
+// BlockStaticInfo is the blocks won by miners of one implementation
+type BlockStaticInfo struct {
+	Implementation string
+	// miners won any block
+	Miners int
+	Blocks int
+	Wins   int64
+	// block reward in FIL
+	Reward float64
+	// share in the QAP of the network
+	QAPShare float64
+	// wins expected from QAPShare of all the wins in range
+	Expected float64
+	// Wins over Expected, an implementation under 1 wins less than its power promises
+	Luck float64
+}
+
+type BlockStatic struct {
+	From, To abi.ChainEpoch
+	Blocks   int
+	Wins     int64
+	// by implementation, ordered by name
+	Implementations []*BlockStaticInfo
+}
+
+// GetBlockStatic compare the blocks won by implementations between epoch from and to with the wins expected
+// from their current QAP share, the last day of blocks recorded if from or to is not positive
+func (a *Api) GetBlockStatic(from, to abi.ChainEpoch) (*BlockStatic, error) {
+	if to <= 0 {
+		var max *abi.ChainEpoch
+		err := a.scope().Model(&BlockInfo{}).Select("max(height)").Scan(&max).Error
+		if err != nil {
+			return nil, err
+		}
+		if max != nil {
+			to = *max
+		}
+	}
+	if from <= 0 {
+		from = to - builtin.EpochsInDay + 1
+	}
+
+	var blocks []BlockInfo
+	err := a.scope().Where("height >= ? and height <= ?", from, to).Find(&blocks).Error
+	if err != nil {
+		return nil, err
+	}
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &BlockStatic{From: from, To: to}
+	statics := make(map[string]*BlockStaticInfo)
+	get := func(impl string) *BlockStaticInfo {
+		s, ok := statics[impl]
+		if !ok {
+			s = &BlockStaticInfo{Implementation: impl}
+			statics[impl] = s
+		}
+		return s
+	}
+
+	// the network QAP only counts miners with min power, which are those could win
+	var networkQAP, minersQAP float64
+	impls := make(map[abi.ActorID]string)
+	for _, miner := range miners {
+		if miner.Power == nil {
+			impls[miner.ID] = implementation(miner.Agent)
+			continue
+		}
+		if miner.ID == NetWork {
+			networkQAP = toPiB(miner.Power.QualityAdjPower)
+			continue
+		}
+		qap := powerOf(*miner.Power, nil).QAP
+		impl := implementation(miner.Agent)
+		impls[miner.ID] = impl
+		get(impl).QAPShare += qap
+		minersQAP += qap
+	}
+	if networkQAP == 0 {
+		networkQAP = minersQAP
+	}
+
+	won := make(map[abi.ActorID]struct{})
+	for _, b := range blocks {
+		impl, ok := impls[b.MinerID]
+		if !ok {
+			impl = ImplUnknown
+		}
+		s := get(impl)
+		s.Blocks++
+		s.Wins += b.WinCount
+		if b.Reward != nil {
+			s.Reward += toFIL(b.Reward)
+		}
+		if _, ok := won[b.MinerID]; !ok {
+			won[b.MinerID] = struct{}{}
+			s.Miners++
+		}
+		ret.Blocks++
+		ret.Wins += b.WinCount
+	}
+
+	for _, s := range statics {
+		if networkQAP > 0 {
+			s.QAPShare /= networkQAP
+		}
+		s.Expected = s.QAPShare * float64(ret.Wins)
+		if s.Expected > 0 {
+			s.Luck = float64(s.Wins) / s.Expected
+		}
+		ret.Implementations = append(ret.Implementations, s)
+	}
+	sort.Slice(ret.Implementations, func(i, j int) bool {
+		return ret.Implementations[i].Implementation < ret.Implementations[j].Implementation
+	})
+	return ret, nil
+}
+
+// toFIL convert attoFIL to FIL, which could overflow uint64
+func toFIL(atto *Power) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(atto.Int), big.NewFloat(1e18)).Float64()
+	return f
+}
+
+// toPiB convert bytes to PiB, the power of network could overflow uint64
+func toPiB(bytes *Power) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(bytes.Int), big.NewFloat(PiB)).Float64()
+	return f
+}
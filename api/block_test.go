@@ -0,0 +1,92 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/test-go/testify/require"
+)
+
+func TestBlockStatic(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1001, Name: "venus-market/v2.8.0"}))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1002, Name: "lotus-1.23.0"}))
+	for _, p := range []PowerInfo{
+		{MinerID: NetWork, RawBytePower: pib(10), QualityAdjPower: pib(10)},
+		{MinerID: 1001, RawBytePower: pib(2), QualityAdjPower: pib(2)},
+		{MinerID: 1002, RawBytePower: pib(8), QualityAdjPower: pib(8)},
+	} {
+		p := p
+		require.NoError(t, api.UpdateMinerPowerInfo(&p))
+	}
+
+	reward := Power(big.Mul(big.NewInt(10), big.NewInt(1e18)))
+	blocks := []BlockInfo{
+		{Cid: "b1", Height: 100, MinerID: 1001, WinCount: 1, Reward: &reward},
+		{Cid: "b2", Height: 100, MinerID: 1002, WinCount: 2, Reward: &reward},
+		{Cid: "b3", Height: 101, MinerID: 1002, WinCount: 1, Reward: &reward},
+		{Cid: "b4", Height: 102, MinerID: 1002, WinCount: 1},
+		{Cid: "b5", Height: 103, MinerID: 1003, WinCount: 1},
+	}
+	require.NoError(t, api.UpdateBlocks(blocks))
+	// saved again
+	require.NoError(t, api.UpdateBlocks(blocks[:1]))
+
+	s, err := api.GetBlockStatic(100, 102)
+	require.NoError(t, err)
+	require.Equal(t, 4, s.Blocks)
+	require.EqualValues(t, 5, s.Wins)
+
+	byImpl := make(map[string]*BlockStaticInfo)
+	for _, i := range s.Implementations {
+		byImpl[i.Implementation] = i
+	}
+	venus, lotus := byImpl[ImplVenus], byImpl[ImplLotus]
+	require.NotNil(t, venus)
+	require.NotNil(t, lotus)
+	require.InDelta(t, 0.2, venus.QAPShare, 1e-9)
+	require.InDelta(t, 1, venus.Expected, 1e-9)
+	require.InDelta(t, 1, venus.Luck, 1e-9)
+	require.EqualValues(t, 4, lotus.Wins)
+	require.Equal(t, 1, lotus.Miners)
+	require.InDelta(t, 20, lotus.Reward, 1e-9)
+
+	// the last day recorded by default
+	s, err = api.GetBlockStatic(0, 0)
+	require.NoError(t, err)
+	require.Equal(t, abi.ChainEpoch(103), s.To)
+	require.Equal(t, 5, s.Blocks)
+	require.NotNil(t, byImplOf(s)[ImplUnknown])
+}
+
+func TestBlockStaticLargeNetwork(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	// 32 EiB of network QAP is above the max of uint64
+	network := Power(big.Lsh(big.NewInt(32), 60))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1001, Name: "venus-market/v2.8.0"}))
+	for _, p := range []PowerInfo{
+		{MinerID: NetWork, RawBytePower: &network, QualityAdjPower: &network},
+		{MinerID: 1001, RawBytePower: pib(1024), QualityAdjPower: pib(1024)},
+	} {
+		p := p
+		require.NoError(t, api.UpdateMinerPowerInfo(&p))
+	}
+	require.NoError(t, api.UpdateBlocks([]BlockInfo{{Cid: "b1", Height: 100, MinerID: 1001, WinCount: 1}}))
+
+	s, err := api.GetBlockStatic(100, 100)
+	require.NoError(t, err)
+	require.InDelta(t, 1024.0/(32*1024), byImplOf(s)[ImplVenus].QAPShare, 1e-9)
+}
+
+func byImplOf(s *BlockStatic) map[string]*BlockStaticInfo {
+	ret := make(map[string]*BlockStaticInfo)
+	for _, i := range s.Implementations {
+		ret[i.Implementation] = i
+	}
+	return ret
+}
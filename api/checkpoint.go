@@ -0,0 +1,28 @@
+package api
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// GetCheckpoint return the checkpoint of job name on the network of Api
+func (a *Api) GetCheckpoint(name string) (*Checkpoint, error) {
+	var cp Checkpoint
+	err := a.scope().First(&cp, "name = ?", name).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCheckpointNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// update Checkpoint, only the latest one of each job is kept
+func (a *Api) UpdateCheckpoint(cp *Checkpoint) error {
+	a.stamp(&cp.Network)
+	return a.db.Save(cp).Error
+}
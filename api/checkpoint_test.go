@@ -0,0 +1,29 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/test-go/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+
+	_, err := api.GetCheckpoint("update-peer")
+	require.Equal(t, ErrCheckpointNotFound, err)
+
+	err = api.UpdateCheckpoint(&Checkpoint{Name: "update-peer", Height: 100, StateRoot: "root100"})
+	require.NoError(t, err)
+	err = api.UpdateCheckpoint(&Checkpoint{Name: "update-peer", Height: 200, StateRoot: "root200"})
+	require.NoError(t, err)
+
+	cp, err := api.GetCheckpoint("update-peer")
+	require.NoError(t, err)
+	require.EqualValues(t, 200, cp.Height)
+	require.Equal(t, "root200", cp.StateRoot)
+
+	_, err = api.GetCheckpoint("watch")
+	require.Equal(t, ErrCheckpointNotFound, err)
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net"
+	"sort"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+const (
+	ClusterByPeer = "peer"
+	ClusterByIP   = "ip"
+)
+
+// Cluster is a group of miners advertising the same peer id or the same public ip,
+// which means they share the infrastructure like a market node
+type Cluster struct {
+	Kind   string
+	Key    string
+	Miners []abi.ActorID
+}
+
+// ClusterMiners find miners sharing peer id or public ip in their latest peer info,
+// only groups of more than one miner are returned
+func ClusterMiners(miners []Miner) []*Cluster {
+	groups := map[string]map[string][]abi.ActorID{
+		ClusterByPeer: make(map[string][]abi.ActorID),
+		ClusterByIP:   make(map[string][]abi.ActorID),
+	}
+	for _, miner := range miners {
+		if miner.Peer == nil {
+			continue
+		}
+		if miner.Peer.PeerId != "" {
+			groups[ClusterByPeer][miner.Peer.PeerId] = append(groups[ClusterByPeer][miner.Peer.PeerId], miner.ID)
+		}
+		if miner.Peer.Multiaddrs != nil {
+			for _, ip := range unique(sliceMap(PublicIPs(*miner.Peer.Multiaddrs), net.IP.String)) {
+				groups[ClusterByIP][ip] = append(groups[ClusterByIP][ip], miner.ID)
+			}
+		}
+	}
+
+	var ret []*Cluster
+	for kind, group := range groups {
+		for key, ids := range group {
+			if len(ids) < 2 {
+				continue
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			ret = append(ret, &Cluster{
+				Kind:   kind,
+				Key:    key,
+				Miners: ids,
+			})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if len(ret[i].Miners) != len(ret[j].Miners) {
+			return len(ret[i].Miners) > len(ret[j].Miners)
+		}
+		if ret[i].Kind != ret[j].Kind {
+			return ret[i].Kind < ret[j].Kind
+		}
+		return ret[i].Key < ret[j].Key
+	})
+	return ret
+}
+
+// PublicIPs return the public ips in multiaddrs, dns and unparsable addresses are skipped
+func PublicIPs(addrs Multiaddrs) []net.IP {
+	var ret []net.IP
+	for _, addr := range addrs {
+		maddr, err := multiaddr.NewMultiaddr(addr)
+		if err != nil || !manet.IsPublicAddr(maddr) {
+			continue
+		}
+		ip, err := manet.ToIP(maddr)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, ip)
+	}
+	return ret
+}
+
+// GetClusters find miners sharing infrastructure by their latest peer info
+func (a *Api) GetClusters() ([]*Cluster, error) {
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+	return ClusterMiners(miners), nil
+}
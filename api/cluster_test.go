@@ -0,0 +1,28 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/test-go/testify/require"
+)
+
+func TestClusterMiners(t *testing.T) {
+	addrs := func(s ...string) *Multiaddrs {
+		m := Multiaddrs(s)
+		return &m
+	}
+	miners := []Miner{
+		{ID: 1001, Peer: &PeerInfo{PeerId: "peer_a", Multiaddrs: addrs("/ip4/8.8.8.8/tcp/1", "/ip4/8.8.8.8/tcp/2")}},
+		{ID: 1002, Peer: &PeerInfo{PeerId: "peer_a", Multiaddrs: addrs("/ip4/192.168.1.1/tcp/1")}},
+		{ID: 1003, Peer: &PeerInfo{PeerId: "peer_b", Multiaddrs: addrs("/ip4/8.8.8.8/tcp/3")}},
+		{ID: 1004, Peer: &PeerInfo{PeerId: "peer_c", Multiaddrs: addrs("/ip4/192.168.1.1/tcp/1")}},
+		{ID: 1005},
+	}
+
+	res := ClusterMiners(miners)
+	require.Equal(t, []*Cluster{
+		{Kind: ClusterByIP, Key: "8.8.8.8", Miners: []abi.ActorID{1001, 1003}},
+		{Kind: ClusterByPeer, Key: "peer_a", Miners: []abi.ActorID{1001, 1002}},
+	}, res)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"sort"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// Entity is a group of miners run by one operator, which is told by sharing
+// owner, worker or beneficiary address
+type Entity struct {
+	// the smallest miner id in the entity
+	ID        abi.ActorID
+	Miners    []abi.ActorID
+	Addresses []string
+
+	// implementation with the most QAP, unknown only when none of the miners is known
+	Implementation string
+	// count of miners by implementation
+	Implementations map[string]int
+
+	StaticInfo
+}
+
+// GetEntities group miners into entities by their latest meta, miners without meta are left out
+func (a *Api) GetEntities() ([]*Entity, error) {
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+
+	// union find over miners, joined by the addresses they share
+	parent := make(map[abi.ActorID]abi.ActorID)
+	var find func(abi.ActorID) abi.ActorID
+	find = func(id abi.ActorID) abi.ActorID {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	union := func(x, y abi.ActorID) {
+		x, y = find(x), find(y)
+		if x == y {
+			return
+		}
+		if x < y {
+			parent[y] = x
+		} else {
+			parent[x] = y
+		}
+	}
+
+	byID := make(map[abi.ActorID]*Miner)
+	owners := make(map[string]abi.ActorID)
+	for i := range miners {
+		miner := &miners[i]
+		if miner.Meta == nil || miner.ID == NetWork {
+			continue
+		}
+		byID[miner.ID] = miner
+		parent[miner.ID] = miner.ID
+		for _, addr := range entityAddresses(miner.Meta) {
+			if other, ok := owners[addr]; ok {
+				union(miner.ID, other)
+			} else {
+				owners[addr] = miner.ID
+			}
+		}
+	}
+
+	entities := make(map[abi.ActorID]*Entity)
+	implQAP := make(map[abi.ActorID]map[string]float64)
+	for id, miner := range byID {
+		root := find(id)
+		e, ok := entities[root]
+		if !ok {
+			e = &Entity{
+				ID:              root,
+				Implementations: make(map[string]int),
+			}
+			entities[root] = e
+			implQAP[root] = make(map[string]float64)
+		}
+		e.Miners = append(e.Miners, id)
+		e.Addresses = append(e.Addresses, entityAddresses(miner.Meta)...)
+
+		impl := implementation(miner.Agent)
+		e.Implementations[impl]++
+		if _, ok := implQAP[root][impl]; !ok {
+			implQAP[root][impl] = 0
+		}
+		if miner.Power != nil {
+			e.add(*miner)
+			implQAP[root][impl] += powerOf(*miner.Power, miner.Sectors).QAP
+		}
+	}
+
+	ret := make([]*Entity, 0, len(entities))
+	for root, e := range entities {
+		sort.Slice(e.Miners, func(i, j int) bool { return e.Miners[i] < e.Miners[j] })
+		e.Addresses = unique(e.Addresses)
+		sort.Strings(e.Addresses)
+		e.Implementation = dominantImplementation(implQAP[root])
+		ret = append(ret, e)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].QAP != ret[j].QAP {
+			return ret[i].QAP > ret[j].QAP
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	return ret, nil
+}
+
+// addresses which mean the miners belong to the same operator,
+// control addresses are left out since they could be shared by hosting services
+func entityAddresses(meta *MinerMeta) []string {
+	var ret []string
+	for _, addr := range []string{meta.Owner, meta.Worker, meta.Beneficiary} {
+		if addr != "" {
+			ret = append(ret, addr)
+		}
+	}
+	return unique(ret)
+}
+
+func dominantImplementation(qap map[string]float64) string {
+	ret := ImplUnknown
+	best := -1.0
+	for _, impl := range []string{ImplVenus, ImplLotus, ImplOthers} {
+		if v, ok := qap[impl]; ok && v > best {
+			ret = impl
+			best = v
+		}
+	}
+	return ret
+}
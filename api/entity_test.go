@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/test-go/testify/require"
+)
+
+func TestEntities(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+
+	// 1001 and 1002 share owner, 1002 and 1003 share beneficiary, 1004 is on its own
+	metas := []MinerMeta{
+		{MinerID: abi.ActorID(1001), Owner: "f0100", Worker: "f0101", Beneficiary: "f0100"},
+		{MinerID: abi.ActorID(1002), Owner: "f0100", Worker: "f0102", Beneficiary: "f0200"},
+		{MinerID: abi.ActorID(1003), Owner: "f0300", Worker: "f0301", Beneficiary: "f0200"},
+		{MinerID: abi.ActorID(1004), Owner: "f0400", Worker: "f0401", Beneficiary: "f0400"},
+	}
+	for _, meta := range metas {
+		err := api.UpdateMinerMeta(&meta)
+		require.NoError(t, err)
+	}
+
+	agents := []AgentInfo{
+		{MinerID: abi.ActorID(1001), Name: "lotus"},
+		{MinerID: abi.ActorID(1002), Name: "venus"},
+		{MinerID: abi.ActorID(1003), Name: "venus"},
+	}
+	for _, agent := range agents {
+		err := api.UpdateMinerAgentInfo(&agent)
+		require.NoError(t, err)
+	}
+
+	powers := []PowerInfo{
+		{MinerID: abi.ActorID(1001), RawBytePower: pib(3), QualityAdjPower: pib(3)},
+		{MinerID: abi.ActorID(1002), RawBytePower: pib(2), QualityAdjPower: pib(2)},
+		{MinerID: abi.ActorID(1003), RawBytePower: pib(2), QualityAdjPower: pib(2)},
+		{MinerID: abi.ActorID(1004), RawBytePower: pib(1), QualityAdjPower: pib(1)},
+	}
+	for _, power := range powers {
+		err := api.UpdateMinerPowerInfo(&power)
+		require.NoError(t, err)
+	}
+
+	res, err := api.GetEntities()
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	require.Equal(t, abi.ActorID(1001), res[0].ID)
+	require.Equal(t, []abi.ActorID{1001, 1002, 1003}, res[0].Miners)
+	require.Equal(t, 7.0, res[0].QAP)
+	require.Equal(t, ImplVenus, res[0].Implementation)
+	require.Equal(t, map[string]int{ImplVenus: 2, ImplLotus: 1}, res[0].Implementations)
+	require.Equal(t, []string{"f0100", "f0101", "f0102", "f0200", "f0300", "f0301"}, res[0].Addresses)
+
+	require.Equal(t, abi.ActorID(1004), res[1].ID)
+	require.Equal(t, ImplUnknown, res[1].Implementation)
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin"
+)
+
+// EpochsInMonth is the epochs of a month of the expiration schedule
+const EpochsInMonth abi.ChainEpoch = 30 * builtin.EpochsInDay
+
+// MonthOf return the first epoch of the month epoch is in, months are counted from genesis
+func MonthOf(epoch abi.ChainEpoch) abi.ChainEpoch {
+	return epoch / EpochsInMonth * EpochsInMonth
+}
+
+// update the expiration schedule of one miner, months without expiring sectors are left out,
+// a miner without active sectors is given a single expiration without sectors to replace its older schedule
+func (a *Api) UpdateMinerExpirations(exps []SectorExpiration) error {
+	if len(exps) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range exps {
+		if exps[i].MinerID != exps[0].MinerID {
+			return fmt.Errorf("expirations belong to different miners: %d, %d", exps[0].MinerID, exps[i].MinerID)
+		}
+		a.stamp(&exps[i].Network)
+		if exps[i].Network != exps[0].Network {
+			return fmt.Errorf("expirations belong to different networks: %s, %s", exps[0].Network, exps[i].Network)
+		}
+		exps[i].UpdatedAt = now
+	}
+
+	err := a.saveMiner(exps[0].MinerID, exps[0].Network)
+	if err != nil {
+		return err
+	}
+	return a.db.Create(&exps).Error
+}
+
+// get the expiration schedule of the latest crawl of every miner
+func (a *Api) getLatestExpirations() ([]SectorExpiration, error) {
+	var exps []SectorExpiration
+	latest := a.scope().Model(&SectorExpiration{}).Select("miner_id, max(updated_at)").Group("miner_id")
+	err := a.scope().Where("(miner_id, updated_at) in (?)", latest).Find(&exps).Error
+	if err != nil {
+		return nil, err
+	}
+	return exps, nil
+}
+
+// ExpirationStaticInfo is the power of miners of one implementation scheduled to expire in one month,
+// and the power left after the month if no sector is extended or sealed, power is in PiB
+type ExpirationStaticInfo struct {
+	// months of 30 days from the one the latest schedule is read in, 0 is the first
+	Month          int
+	Implementation string
+	// miners with sectors expiring in the month
+	Miners  int
+	Sectors uint64
+	RBP     float64
+	QAP     float64
+	// RBP of expiring sectors without deals, the rest is of sectors with deals
+	CCRBP float64
+	// current power less all the expirations up to the end of the month
+	RemainingRBP float64
+	RemainingQAP float64
+	// share in the QAP left in the network after the month
+	QAPShare float64
+}
+
+// GetExpirationStatic forecast the power of implementations in the next months from their current power and
+// the latest expiration schedules of their miners, miners without a schedule are taken as expiring nothing
+func (a *Api) GetExpirationStatic(months int) ([]*ExpirationStaticInfo, error) {
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+	exps, err := a.getLatestExpirations()
+	if err != nil {
+		return nil, err
+	}
+
+	type power struct {
+		rbp, qap float64
+	}
+	// current power by implementation, and that of the network
+	current := make(map[string]*power)
+	var network, minersPower power
+	impls := make(map[abi.ActorID]string)
+	for _, miner := range miners {
+		if miner.Power == nil {
+			continue
+		}
+		if miner.ID == NetWork {
+			network = power{toPiB(miner.Power.RawBytePower), toPiB(miner.Power.QualityAdjPower)}
+			continue
+		}
+		p := powerOf(*miner.Power, nil)
+		impl := implementation(miner.Agent)
+		impls[miner.ID] = impl
+		if current[impl] == nil {
+			current[impl] = &power{}
+		}
+		current[impl].rbp += p.RBP
+		current[impl].qap += p.QAP
+		minersPower.rbp += p.RBP
+		minersPower.qap += p.QAP
+	}
+	if network.qap == 0 {
+		network = minersPower
+	}
+
+	type key struct {
+		month int
+		impl  string
+	}
+	// months are counted from the one of the latest schedule, sectors of older schedules expiring before
+	// are in the first, as the power recorded with those schedules still has them
+	var latest abi.ChainEpoch
+	for _, e := range exps {
+		if e.Height > latest {
+			latest = e.Height
+		}
+	}
+	first := MonthOf(latest)
+
+	statics := make(map[key]*ExpirationStaticInfo)
+	for _, e := range exps {
+		impl, ok := impls[e.MinerID]
+		if !ok || e.Sectors == 0 {
+			continue
+		}
+		month := 0
+		if e.Epoch > first {
+			month = int((e.Epoch - first) / EpochsInMonth)
+		}
+		if month >= months {
+			continue
+		}
+		k := key{month, impl}
+		s, ok := statics[k]
+		if !ok {
+			s = &ExpirationStaticInfo{Month: month, Implementation: impl}
+			statics[k] = s
+		}
+		s.Miners++
+		s.Sectors += e.Sectors
+		if e.RawBytes != nil {
+			s.RBP += float64(e.RawBytes.Uint64()) / PiB
+		}
+		if e.QABytes != nil {
+			s.QAP += float64(e.QABytes.Uint64()) / PiB
+		}
+		if e.CCBytes != nil {
+			s.CCRBP += float64(e.CCBytes.Uint64()) / PiB
+		}
+	}
+
+	names := make([]string, 0, len(current))
+	for impl := range current {
+		names = append(names, impl)
+	}
+	sort.Strings(names)
+
+	ret := make([]*ExpirationStaticInfo, 0, months*len(names))
+	remaining := make(map[string]power, len(names))
+	for _, impl := range names {
+		remaining[impl] = *current[impl]
+	}
+	networkQAP := network.qap
+	for month := 0; month < months; month++ {
+		rows := make([]*ExpirationStaticInfo, 0, len(names))
+		for _, impl := range names {
+			s, ok := statics[key{month, impl}]
+			if !ok {
+				s = &ExpirationStaticInfo{Month: month, Implementation: impl}
+			}
+			left := remaining[impl]
+			left.rbp -= s.RBP
+			left.qap -= s.QAP
+			remaining[impl] = left
+			networkQAP -= s.QAP
+
+			s.RemainingRBP = left.rbp
+			s.RemainingQAP = left.qap
+			rows = append(rows, s)
+		}
+		for _, s := range rows {
+			if networkQAP > 0 {
+				s.QAPShare = s.RemainingQAP / networkQAP
+			}
+		}
+		ret = append(ret, rows...)
+	}
+	return ret, nil
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/test-go/testify/require"
+)
+
+func TestExpirationStatic(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1001, Name: "venus-market/v2.8.0"}))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1002, Name: "lotus-1.23.0"}))
+	for _, id := range []abi.ActorID{1001, 1002} {
+		require.NoError(t, api.UpdateMinerPowerInfo(&PowerInfo{MinerID: id, RawBytePower: pib(4), QualityAdjPower: pib(4)}))
+	}
+
+	// replaced by the next schedule of the miner
+	require.NoError(t, api.UpdateMinerExpirations([]SectorExpiration{
+		{MinerID: 1001, Epoch: 0, Sectors: 10, RawBytes: pib(4), QABytes: pib(4), CCBytes: pib(4)},
+	}))
+	require.NoError(t, api.UpdateMinerExpirations([]SectorExpiration{
+		{MinerID: 1001, Epoch: 0, Sectors: 1, RawBytes: pib(1), QABytes: pib(1), CCBytes: pib(1)},
+		{MinerID: 1001, Epoch: 2 * EpochsInMonth, Sectors: 1, RawBytes: pib(1), QABytes: pib(1), CCBytes: pib(0)},
+		// beyond the months asked
+		{MinerID: 1001, Epoch: 5 * EpochsInMonth, Sectors: 1, RawBytes: pib(1), QABytes: pib(1), CCBytes: pib(0)},
+	}))
+	require.Error(t, api.UpdateMinerExpirations([]SectorExpiration{{MinerID: 1001}, {MinerID: 1002}}))
+
+	s, err := api.GetExpirationStatic(3)
+	require.NoError(t, err)
+	require.Len(t, s, 6)
+
+	byKey := make(map[[2]interface{}]*ExpirationStaticInfo)
+	for _, e := range s {
+		byKey[[2]interface{}{e.Month, e.Implementation}] = e
+	}
+
+	first := byKey[[2]interface{}{0, ImplVenus}]
+	require.Equal(t, 1, first.Miners)
+	require.EqualValues(t, 1, first.Sectors)
+	require.InDelta(t, 1, first.CCRBP, 1e-9)
+	require.InDelta(t, 3, first.RemainingQAP, 1e-9)
+	require.InDelta(t, 3.0/7, first.QAPShare, 1e-9)
+	require.InDelta(t, 4.0/7, byKey[[2]interface{}{0, ImplLotus}].QAPShare, 1e-9)
+
+	// nothing expires in the second month
+	second := byKey[[2]interface{}{1, ImplVenus}]
+	require.Equal(t, 0, second.Miners)
+	require.InDelta(t, 3, second.RemainingRBP, 1e-9)
+
+	third := byKey[[2]interface{}{2, ImplVenus}]
+	require.InDelta(t, 0, third.CCRBP, 1e-9)
+	require.InDelta(t, 2, third.RemainingQAP, 1e-9)
+	require.InDelta(t, 2.0/6, third.QAPShare, 1e-9)
+	require.InDelta(t, 4.0/6, byKey[[2]interface{}{2, ImplLotus}].QAPShare, 1e-9)
+}
+
+func TestExpirationStaticLargeNetwork(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	// 32 EiB of network QAP is above the max of uint64
+	network := Power(big.Lsh(big.NewInt(32), 60))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1001, Name: "venus-market/v2.8.0"}))
+	for _, p := range []PowerInfo{
+		{MinerID: NetWork, RawBytePower: &network, QualityAdjPower: &network},
+		{MinerID: 1001, RawBytePower: pib(1024), QualityAdjPower: pib(1024)},
+	} {
+		p := p
+		require.NoError(t, api.UpdateMinerPowerInfo(&p))
+	}
+	require.NoError(t, api.UpdateMinerExpirations([]SectorExpiration{
+		{MinerID: 1001, Epoch: 0, Sectors: 1, RawBytes: pib(512), QABytes: pib(512), CCBytes: pib(512)},
+	}))
+
+	s, err := api.GetExpirationStatic(1)
+	require.NoError(t, err)
+	require.Len(t, s, 1)
+	require.InDelta(t, 512, s[0].RemainingQAP, 1e-9)
+	require.InDelta(t, 512.0/(32*1024-512), s[0].QAPShare, 1e-9)
+}
+
+func TestExpirationStaticHeights(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1001, Name: "venus-market/v2.8.0"}))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1002, Name: "lotus-1.23.0"}))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1003, Name: "lotus-1.23.0"}))
+	for _, id := range []abi.ActorID{1001, 1002, 1003} {
+		require.NoError(t, api.UpdateMinerPowerInfo(&PowerInfo{MinerID: id, RawBytePower: pib(4), QualityAdjPower: pib(4)}))
+	}
+
+	// read a month apart, the sectors expire in the same month
+	require.NoError(t, api.UpdateMinerExpirations([]SectorExpiration{
+		{MinerID: 1001, Height: 100, Epoch: 2 * EpochsInMonth, Sectors: 1, RawBytes: pib(1), QABytes: pib(1), CCBytes: pib(1)},
+	}))
+	require.NoError(t, api.UpdateMinerExpirations([]SectorExpiration{
+		{MinerID: 1002, Height: EpochsInMonth + 100, Epoch: 2 * EpochsInMonth, Sectors: 1, RawBytes: pib(1), QABytes: pib(1), CCBytes: pib(1)},
+	}))
+	// all the sectors expired since, the empty schedule replaces the older one
+	require.NoError(t, api.UpdateMinerExpirations([]SectorExpiration{
+		{MinerID: 1003, Height: 100, Epoch: EpochsInMonth, Sectors: 1, RawBytes: pib(4), QABytes: pib(4), CCBytes: pib(4)},
+	}))
+	require.NoError(t, api.UpdateMinerExpirations([]SectorExpiration{
+		{MinerID: 1003, Height: EpochsInMonth + 100, Epoch: EpochsInMonth},
+	}))
+
+	s, err := api.GetExpirationStatic(2)
+	require.NoError(t, err)
+	require.Len(t, s, 4)
+
+	byKey := make(map[[2]interface{}]*ExpirationStaticInfo)
+	for _, e := range s {
+		byKey[[2]interface{}{e.Month, e.Implementation}] = e
+	}
+	require.Equal(t, 0, byKey[[2]interface{}{0, ImplLotus}].Miners)
+	require.Equal(t, 0, byKey[[2]interface{}{0, ImplVenus}].Miners)
+	lotus := byKey[[2]interface{}{1, ImplLotus}]
+	require.Equal(t, 1, lotus.Miners)
+	require.InDelta(t, 7, lotus.RemainingQAP, 1e-9)
+	require.Equal(t, 1, byKey[[2]interface{}{1, ImplVenus}].Miners)
+}
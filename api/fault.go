@@ -0,0 +1,122 @@
+package api
+
+import (
+	"sort"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// update Miner FaultInfo
+func (a *Api) UpdateMinerFaultInfo(faults *FaultInfo) error {
+	a.stamp(&faults.Network)
+	err := a.saveMiner(faults.MinerID, faults.Network)
+	if err != nil {
+		return err
+	}
+	err = a.db.Create(faults).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// FaultStaticInfo is the faults of miners of one implementation on one day
+type FaultStaticInfo struct {
+	// UTC day in 2006-01-02
+	Day            string
+	Implementation string
+	Miners         int
+	Faults         uint64
+	Recoveries     uint64
+	// raw power in PiB lost to faults, and that still claimed
+	FaultyRBP float64
+	RBP       float64
+	// FaultyRBP in the raw power the miners had before faults
+	FaultRate float64
+}
+
+// GetFaultStatic compare faults between implementations by day in the last days, the latest records
+// of a miner on each day are counted, miners are classified by their latest agent
+func (a *Api) GetFaultStatic(days int) ([]*FaultStaticInfo, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	day := func(t time.Time) string {
+		return t.UTC().Format("2006-01-02")
+	}
+
+	var faults []FaultInfo
+	err := a.scope().Where("updated_at >= ?", since).Order("updated_at").Find(&faults).Error
+	if err != nil {
+		return nil, err
+	}
+	var powers []PowerInfo
+	err = a.scope().Where("updated_at >= ? and miner_id in ?", since, unique(sliceMap(faults, func(f FaultInfo) abi.ActorID { return f.MinerID }))).Order("updated_at").Find(&powers).Error
+	if err != nil {
+		return nil, err
+	}
+	agents, err := a.getAgents(sliceMap(faults, func(f FaultInfo) abi.ActorID { return f.MinerID })...)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		day   string
+		miner abi.ActorID
+	}
+	// records are in order, so the latest of a day wins
+	dayFaults := make(map[key]FaultInfo)
+	for _, f := range faults {
+		dayFaults[key{day(f.UpdatedAt), f.MinerID}] = f
+	}
+	dayPowers := make(map[key]PowerInfo)
+	for _, p := range powers {
+		dayPowers[key{day(p.UpdatedAt), p.MinerID}] = p
+	}
+	impls := make(map[abi.ActorID]string)
+	for i := range agents {
+		impls[agents[i].MinerID] = implementation(&agents[i])
+	}
+
+	statics := make(map[[2]string]*FaultStaticInfo)
+	for k, f := range dayFaults {
+		p, hasPower := dayPowers[k]
+		if hasPower && !a.selected(&p) {
+			continue
+		}
+
+		impl, ok := impls[k.miner]
+		if !ok {
+			impl = ImplUnknown
+		}
+		s, ok := statics[[2]string{k.day, impl}]
+		if !ok {
+			s = &FaultStaticInfo{Day: k.day, Implementation: impl}
+			statics[[2]string{k.day, impl}] = s
+		}
+
+		s.Miners++
+		s.Faults += f.Faults
+		s.Recoveries += f.Recoveries
+		if f.FaultyBytes != nil {
+			s.FaultyRBP += float64(f.FaultyBytes.Uint64()) / PiB
+		}
+		if hasPower && p.RawBytePower != nil {
+			s.RBP += float64(p.RawBytePower.Uint64()) / PiB
+		}
+	}
+
+	ret := make([]*FaultStaticInfo, 0, len(statics))
+	for _, s := range statics {
+		if total := s.RBP + s.FaultyRBP; total > 0 {
+			s.FaultRate = s.FaultyRBP / total
+		}
+		ret = append(ret, s)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Day != ret[j].Day {
+			return ret[i].Day < ret[j].Day
+		}
+		return ret[i].Implementation < ret[j].Implementation
+	})
+	return ret, nil
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/test-go/testify/require"
+)
+
+func TestFaultStatic(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1001, Name: "venus-market/v2.8.0"}))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1002, Name: "lotus-1.23.0"}))
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	for _, p := range []PowerInfo{
+		{MinerID: 1001, RawBytePower: pib(3), QualityAdjPower: pib(3), UpdatedAt: yesterday},
+		{MinerID: 1001, RawBytePower: pib(4), QualityAdjPower: pib(4)},
+		{MinerID: 1002, RawBytePower: pib(9), QualityAdjPower: pib(9)},
+	} {
+		p := p
+		require.NoError(t, api.UpdateMinerPowerInfo(&p))
+	}
+	for _, f := range []FaultInfo{
+		{MinerID: 1001, Faults: 10, Recoveries: 2, FaultyBytes: pib(1), UpdatedAt: yesterday},
+		{MinerID: 1001, Faults: 0, Recoveries: 0, FaultyBytes: pib(0)},
+		{MinerID: 1002, Faults: 5, Recoveries: 5, FaultyBytes: pib(1)},
+		// too old to be counted
+		{MinerID: 1002, Faults: 5, FaultyBytes: pib(1), UpdatedAt: time.Now().AddDate(0, 0, -10)},
+	} {
+		f := f
+		require.NoError(t, api.UpdateMinerFaultInfo(&f))
+	}
+
+	statics, err := api.GetFaultStatic(7)
+	require.NoError(t, err)
+	require.Len(t, statics, 3)
+
+	day := func(t time.Time) string { return t.UTC().Format("2006-01-02") }
+	byKey := make(map[[2]string]*FaultStaticInfo)
+	for _, s := range statics {
+		byKey[[2]string{s.Day, s.Implementation}] = s
+	}
+
+	s := byKey[[2]string{day(yesterday), ImplVenus}]
+	require.NotNil(t, s)
+	require.Equal(t, 1, s.Miners)
+	require.EqualValues(t, 10, s.Faults)
+	require.InDelta(t, 0.25, s.FaultRate, 1e-9)
+
+	s = byKey[[2]string{day(time.Now()), ImplVenus}]
+	require.NotNil(t, s)
+	require.EqualValues(t, 0, s.Faults)
+	require.Zero(t, s.FaultRate)
+
+	s = byKey[[2]string{day(time.Now()), ImplLotus}]
+	require.NotNil(t, s)
+	require.EqualValues(t, 5, s.Recoveries)
+	require.InDelta(t, 0.1, s.FaultRate, 1e-9)
+
+	detail, err := api.GetMinerDetail(abi.ActorID(1002))
+	require.NoError(t, err)
+	require.NotNil(t, detail.Faults)
+}
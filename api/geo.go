@@ -0,0 +1,61 @@
+package api
+
+// update Miner GeoInfo
+func (a *Api) UpdateMinerGeoInfo(geo *GeoInfo) error {
+	a.stamp(&geo.Network)
+	err := a.saveMiner(geo.MinerID, geo.Network)
+	if err != nil {
+		return err
+	}
+	err = a.db.Create(geo).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type GeoStaticInfo struct {
+	// static of all miners by country
+	Country map[string]*StaticInfo
+	// static by implementation then by country
+	Implementation map[string]map[string]*StaticInfo
+}
+
+// GetGeoStatic aggregate the power of miners by the country of their latest geo info,
+// miners without geo info are counted into unknown country
+func (a *Api) GetGeoStatic() (*GeoStaticInfo, error) {
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &GeoStaticInfo{
+		Country:        make(map[string]*StaticInfo),
+		Implementation: make(map[string]map[string]*StaticInfo),
+	}
+	for _, miner := range miners {
+		if miner.ID == NetWork || miner.Power == nil {
+			continue
+		}
+
+		country := "unknown"
+		if miner.Geo != nil && miner.Geo.Country != "" {
+			country = miner.Geo.Country
+		}
+		impl := implementation(miner.Agent)
+
+		if ret.Country[country] == nil {
+			ret.Country[country] = &StaticInfo{}
+		}
+		if ret.Implementation[impl] == nil {
+			ret.Implementation[impl] = make(map[string]*StaticInfo)
+		}
+		if ret.Implementation[impl][country] == nil {
+			ret.Implementation[impl][country] = &StaticInfo{}
+		}
+
+		ret.Country[country].add(miner)
+		ret.Implementation[impl][country].add(miner)
+	}
+	return ret, nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/test-go/testify/require"
+)
+
+func TestGeoStatic(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+
+	agents := []AgentInfo{
+		{MinerID: abi.ActorID(1001), Name: "venus"},
+		{MinerID: abi.ActorID(1002), Name: "venus"},
+		{MinerID: abi.ActorID(1003), Name: "lotus"},
+	}
+	for _, agent := range agents {
+		err := api.UpdateMinerAgentInfo(&agent)
+		require.NoError(t, err)
+	}
+
+	powers := []PowerInfo{
+		{MinerID: abi.ActorID(1001), RawBytePower: pib(1), QualityAdjPower: pib(1)},
+		{MinerID: abi.ActorID(1002), RawBytePower: pib(2), QualityAdjPower: pib(2)},
+		{MinerID: abi.ActorID(1003), RawBytePower: pib(3), QualityAdjPower: pib(3)},
+	}
+	for _, power := range powers {
+		err := api.UpdateMinerPowerInfo(&power)
+		require.NoError(t, err)
+	}
+
+	geos := []GeoInfo{
+		{MinerID: abi.ActorID(1001), IP: "1.1.1.1", Country: "US"},
+		{MinerID: abi.ActorID(1001), IP: "2.2.2.2", Country: "CN"},
+		{MinerID: abi.ActorID(1003), IP: "3.3.3.3", Country: "CN"},
+	}
+	for _, geo := range geos {
+		err := api.UpdateMinerGeoInfo(&geo)
+		require.NoError(t, err)
+	}
+
+	res, err := api.GetGeoStatic()
+	require.NoError(t, err)
+	require.Len(t, res.Country, 2)
+	require.Equal(t, 2, res.Country["CN"].Count)
+	require.Equal(t, 4.0, res.Country["CN"].RBP)
+	require.Equal(t, 1, res.Country["unknown"].Count)
+
+	require.Equal(t, 1.0, res.Implementation[ImplVenus]["CN"].QAP)
+	require.Equal(t, 2.0, res.Implementation[ImplVenus]["unknown"].QAP)
+	require.Equal(t, 3.0, res.Implementation[ImplLotus]["CN"].QAP)
+}
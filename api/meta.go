@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// update Miner MinerMeta
+func (a *Api) UpdateMinerMeta(meta *MinerMeta) error {
+	a.stamp(&meta.Network)
+	err := a.saveMiner(meta.MinerID, meta.Network)
+	if err != nil {
+		return err
+	}
+	err = a.db.Create(meta).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type MetaStaticInfo struct {
+	// static by implementation then by sector size like 32GiB
+	SectorSize map[string]map[string]*StaticInfo
+	// static by implementation then by window post proof type
+	ProofType map[string]map[string]*StaticInfo
+}
+
+// GetMetaStatic aggregate the power of miners by their sector size and proof type
+func (a *Api) GetMetaStatic() (*MetaStaticInfo, error) {
+	miners, err := a.GetAllMiners()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &MetaStaticInfo{
+		SectorSize: make(map[string]map[string]*StaticInfo),
+		ProofType:  make(map[string]map[string]*StaticInfo),
+	}
+	update := func(m map[string]map[string]*StaticInfo, impl, key string, miner Miner) {
+		if m[impl] == nil {
+			m[impl] = make(map[string]*StaticInfo)
+		}
+		if m[impl][key] == nil {
+			m[impl][key] = &StaticInfo{}
+		}
+		m[impl][key].add(miner)
+	}
+
+	for _, miner := range miners {
+		if miner.Meta == nil || miner.Power == nil {
+			continue
+		}
+		impl := implementation(miner.Agent)
+		update(ret.SectorSize, impl, miner.Meta.SectorSize.ShortString(), miner)
+		update(ret.ProofType, impl, postProofName(miner.Meta.WindowPoStProofType), miner)
+	}
+	return ret, nil
+}
+
+func postProofName(p abi.RegisteredPoStProof) string {
+	switch p {
+	case abi.RegisteredPoStProof_StackedDrgWindow2KiBV1:
+		return "StackedDrgWindow2KiBV1"
+	case abi.RegisteredPoStProof_StackedDrgWindow8MiBV1:
+		return "StackedDrgWindow8MiBV1"
+	case abi.RegisteredPoStProof_StackedDrgWindow512MiBV1:
+		return "StackedDrgWindow512MiBV1"
+	case abi.RegisteredPoStProof_StackedDrgWindow32GiBV1:
+		return "StackedDrgWindow32GiBV1"
+	case abi.RegisteredPoStProof_StackedDrgWindow64GiBV1:
+		return "StackedDrgWindow64GiBV1"
+	case abi.RegisteredPoStProof_StackedDrgWindow2KiBV1_1:
+		return "StackedDrgWindow2KiBV1_1"
+	case abi.RegisteredPoStProof_StackedDrgWindow8MiBV1_1:
+		return "StackedDrgWindow8MiBV1_1"
+	case abi.RegisteredPoStProof_StackedDrgWindow512MiBV1_1:
+		return "StackedDrgWindow512MiBV1_1"
+	case abi.RegisteredPoStProof_StackedDrgWindow32GiBV1_1:
+		return "StackedDrgWindow32GiBV1_1"
+	case abi.RegisteredPoStProof_StackedDrgWindow64GiBV1_1:
+		return "StackedDrgWindow64GiBV1_1"
+	default:
+		return fmt.Sprintf("RegisteredPoStProof(%d)", p)
+	}
+}
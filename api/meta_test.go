@@ -0,0 +1,46 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/test-go/testify/require"
+)
+
+func TestMetaStatic(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+
+	controls := Addresses{"f01", "f02"}
+	metas := []MinerMeta{
+		{MinerID: abi.ActorID(1001), Owner: "f0100", ControlAddresses: &controls, SectorSize: 32 << 30, WindowPoStProofType: abi.RegisteredPoStProof_StackedDrgWindow32GiBV1_1},
+		{MinerID: abi.ActorID(1002), Owner: "f0100", SectorSize: 64 << 30, WindowPoStProofType: abi.RegisteredPoStProof_StackedDrgWindow64GiBV1},
+	}
+	for _, meta := range metas {
+		err := api.UpdateMinerMeta(&meta)
+		require.NoError(t, err)
+	}
+
+	err := api.UpdateMinerAgentInfo(&AgentInfo{MinerID: abi.ActorID(1001), Name: "venus"})
+	require.NoError(t, err)
+	for _, power := range []PowerInfo{
+		{MinerID: abi.ActorID(1001), RawBytePower: pib(1), QualityAdjPower: pib(1)},
+		{MinerID: abi.ActorID(1002), RawBytePower: pib(2), QualityAdjPower: pib(2)},
+	} {
+		err := api.UpdateMinerPowerInfo(&power)
+		require.NoError(t, err)
+	}
+
+	miners, err := api.GetAllMiners()
+	require.NoError(t, err)
+	require.Len(t, miners, 2)
+	require.Equal(t, &controls, miners[0].Meta.ControlAddresses)
+
+	res, err := api.GetMetaStatic()
+	require.NoError(t, err)
+	require.Equal(t, 1.0, res.SectorSize[ImplVenus]["32GiB"].RBP)
+	require.Equal(t, 2.0, res.SectorSize[ImplUnknown]["64GiB"].RBP)
+	require.Equal(t, 1, res.ProofType[ImplVenus]["StackedDrgWindow32GiBV1_1"].Count)
+	require.Equal(t, 1, res.ProofType[ImplUnknown]["StackedDrgWindow64GiBV1"].Count)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"gorm.io/gorm"
+)
+
+var ErrMinerNotFound = errors.New("miner not found")
+
+// ParseMinerID accept an ID address like f0123, t0123 or the bare actor id 123
+func ParseMinerID(s string) (abi.ActorID, error) {
+	if id, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return abi.ActorID(id), nil
+	}
+
+	addr, err := address.NewFromString(s)
+	if err != nil {
+		return 0, fmt.Errorf("parse miner address %s: %w", s, err)
+	}
+	id, err := address.IDFromAddress(addr)
+	if err != nil {
+		return 0, fmt.Errorf("miner address %s is not an ID address: %w", s, err)
+	}
+	return abi.ActorID(id), nil
+}
+
+type MinerDetail struct {
+	Miner
+
+	Implementation string
+	HasDeal        bool
+
+	// power in PiB
+	RBP float64
+	QAP float64
+	DCP float64
+	CCP float64
+
+	// seconds since the latest record of each kind updated
+	Ages map[string]int64
+}
+
+// GetMinerDetail return the latest records of miner with fields derived from them
+func (a *Api) GetMinerDetail(id abi.ActorID) (*MinerDetail, error) {
+	// the power of one miner is shown whether it reaches min power or not
+	miner, err := a.WithBelowMin(BelowMinInclude).getMiner(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMinerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &MinerDetail{
+		Miner:          *miner,
+		Implementation: implementation(miner.Agent),
+		Ages:           make(map[string]int64),
+	}
+
+	if miner.Power != nil {
+		mp := powerOf(*miner.Power, miner.Sectors)
+		ret.RBP, ret.QAP, ret.DCP, ret.CCP = mp.RBP, mp.QAP, mp.DCP, mp.CCP
+		ret.HasDeal = ret.DCP > 0.0000000001
+	}
+
+	age := func(name string, updatedAt time.Time) {
+		ret.Ages[name] = int64(time.Since(updatedAt).Seconds())
+	}
+	if miner.Power != nil {
+		age("power", miner.Power.UpdatedAt)
+	}
+	if miner.Peer != nil {
+		age("peer", miner.Peer.UpdatedAt)
+	}
+	if miner.Agent != nil {
+		age("agent", miner.Agent.UpdatedAt)
+	}
+	if miner.Identify != nil {
+		age("identify", miner.Identify.UpdatedAt)
+	}
+	if miner.Probe != nil {
+		age("probe", miner.Probe.UpdatedAt)
+	}
+	if miner.Geo != nil {
+		age("geo", miner.Geo.UpdatedAt)
+	}
+	if miner.Meta != nil {
+		age("meta", miner.Meta.UpdatedAt)
+	}
+	if miner.Sectors != nil {
+		age("sectors", miner.Sectors.UpdatedAt)
+	}
+	if miner.Faults != nil {
+		age("faults", miner.Faults.UpdatedAt)
+	}
+	if miner.Balance != nil {
+		age("balance", miner.Balance.UpdatedAt)
+	}
+	return ret, nil
+}
+
+// GetMinersByImpl return miners of implementation impl, see ImplVenus and the others, all miners if impl is empty
+func (a *Api) GetMinersByImpl(impl string) ([]Miner, error) {
+	impl, err := ParseImpl(impl)
+	if err != nil {
+		return nil, err
+	}
+	miners, err := a.GetAllMiners()
+	if err != nil || impl == "" {
+		return miners, err
+	}
+
+	ret := []Miner{}
+	for _, miner := range miners {
+		if implementation(miner.Agent) == impl {
+			ret = append(ret, miner)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/test-go/testify/require"
+)
+
+func TestParseMinerID(t *testing.T) {
+	for _, s := range []string{"f01234", "t01234", "1234"} {
+		id, err := ParseMinerID(s)
+		require.NoError(t, err, s)
+		require.Equal(t, abi.ActorID(1234), id, s)
+	}
+
+	for _, s := range []string{"", "f0abc", "x01234", "f1abjxfbp274xpdqcpuaykwkfb43omjotacm2p3za"} {
+		_, err := ParseMinerID(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestMinerDetail(t *testing.T) {
+	db := newDB(t)
+
+	api := NewApi(db)
+	miner := abi.ActorID(1002)
+
+	err := api.UpdateMinerAgentInfo(&AgentInfo{MinerID: miner, Name: "venus-market/v2.8.0"})
+	require.NoError(t, err)
+	err = api.UpdateMinerPowerInfo(&PowerInfo{MinerID: miner, RawBytePower: pib(10), QualityAdjPower: pib(19)})
+	require.NoError(t, err)
+
+	res, err := api.GetMinerDetail(miner)
+	require.NoError(t, err)
+	require.Equal(t, miner, res.ID)
+	require.Equal(t, ImplVenus, res.Implementation)
+	require.True(t, res.HasDeal)
+	require.Equal(t, 1.0, res.DCP)
+	require.Equal(t, 9.0, res.CCP)
+	require.Contains(t, res.Ages, "power")
+	require.Contains(t, res.Ages, "agent")
+	require.NotContains(t, res.Ages, "peer")
+
+	_, err = api.GetMinerDetail(abi.ActorID(1003))
+	require.Equal(t, ErrMinerNotFound, err)
+}
+
+func TestMinersByImpl(t *testing.T) {
+	db := newDB(t)
+	api := NewApi(db)
+
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1001, Name: "venus-market/v2.8.0"}))
+	require.NoError(t, api.UpdateMinerAgentInfo(&AgentInfo{MinerID: 1002, Name: "lotus-1.23.0"}))
+	require.NoError(t, api.UpdateMinerPowerInfo(&PowerInfo{MinerID: 1003, RawBytePower: pib(1), QualityAdjPower: pib(1)}))
+
+	for impl, expect := range map[string][]abi.ActorID{
+		ImplVenus:   {1001},
+		ImplLotus:   {1002},
+		ImplUnknown: {1003},
+		"":          {1001, 1002, 1003},
+	} {
+		miners, err := api.GetMinersByImpl(impl)
+		require.NoError(t, err, impl)
+		ids := []abi.ActorID{}
+		for _, m := range miners {
+			ids = append(ids, m.ID)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		require.Equal(t, expect, ids, impl)
+	}
+
+	_, err := api.GetMinersByImpl("Venus")
+	require.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+	"gorm.io/gorm"
+)
+
+// DefaultNetwork is the network of records which don't tell theirs, including those stored before networks were recorded
+const DefaultNetwork = "mainnet"
+
+// ForNetwork return an Api reading records of network, the default network if empty
+func (a *Api) ForNetwork(network string) *Api {
+	if network == "" {
+		network = DefaultNetwork
+	}
+	return &Api{db: a.db, network: network, belowMin: a.belowMin}
+}
+
+// Network return the network the Api reads from
+func (a *Api) Network() string {
+	return a.network
+}
+
+// scope limit queries to the network of Api
+func (a *Api) scope() *gorm.DB {
+	return a.db.Where("network = ?", a.network)
+}
+
+// stamp fill the network of a record which doesn't tell its
+func (a *Api) stamp(network *string) {
+	if *network == "" {
+		*network = a.network
+	}
+}
+
+// saveMiner register miner on network
+func (a *Api) saveMiner(id abi.ActorID, network string) error {
+	err := a.db.Save(&Miner{ID: id}).Error
+	if err != nil {
+		return err
+	}
+	return a.db.Save(&MinerNetwork{MinerID: id, Network: network}).Error
+}
+
+// backfillNetwork assign records stored before networks were recorded to the default network
+func (a *Api) backfillNetwork() error {
+	for _, model := range []interface{}{&PeerInfo{}, &PowerInfo{}, &AgentInfo{}, &IdentifyInfo{}, &ProbeResult{}, &AddrProbe{}, &GeoInfo{}, &MinerMeta{}, &SectorPower{}, &FaultInfo{}, &BalanceInfo{}, &SectorExpiration{}, &BlockInfo{}, &Checkpoint{}} {
+		err := a.db.Model(model).Where("network = ? or network is null", "").Update("network", DefaultNetwork).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	var count int64
+	err := a.db.Model(&MinerNetwork{}).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+	var ids []abi.ActorID
+	err = a.db.Model(&Miner{}).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return a.db.Create(sliceMap(ids, func(id abi.ActorID) MinerNetwork {
+		return MinerNetwork{MinerID: id, Network: DefaultNetwork}
+	})).Error
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/test-go/testify/require"
+)
+
+func TestNetwork(t *testing.T) {
+	db := newDB(t)
+
+	// records stored before networks were recorded
+	err := db.AutoMigrate(&Miner{}, &PowerInfo{}, &AgentInfo{})
+	require.NoError(t, err)
+	err = db.Create(&Miner{ID: abi.ActorID(1001)}).Error
+	require.NoError(t, err)
+	err = db.Create(&PowerInfo{MinerID: abi.ActorID(1001), RawBytePower: pib(1), QualityAdjPower: pib(1)}).Error
+	require.NoError(t, err)
+	err = db.Create(&AgentInfo{MinerID: abi.ActorID(1001), Name: "venus"}).Error
+	require.NoError(t, err)
+
+	mainnet := NewApi(db)
+	require.Equal(t, DefaultNetwork, mainnet.Network())
+	calib := mainnet.ForNetwork("calibrationnet")
+
+	// the same actor id on another network
+	err = calib.UpdateMinerPowerInfo(&PowerInfo{MinerID: abi.ActorID(1001), RawBytePower: pib(5), QualityAdjPower: pib(5)})
+	require.NoError(t, err)
+	err = calib.UpdateMinerAgentInfo(&AgentInfo{MinerID: abi.ActorID(1001), Name: "lotus"})
+	require.NoError(t, err)
+	// records telling their network are stored there whichever Api receives them
+	err = mainnet.UpdateMinerPowerInfo(&PowerInfo{MinerID: abi.ActorID(1002), Network: "calibrationnet", RawBytePower: pib(2), QualityAdjPower: pib(2)})
+	require.NoError(t, err)
+
+	miners, err := mainnet.GetAllMiners()
+	require.NoError(t, err)
+	require.Len(t, miners, 1)
+	require.Equal(t, DefaultNetwork, miners[0].Power.Network)
+	require.Equal(t, "venus", miners[0].Agent.Name)
+
+	miners, err = calib.GetAllMiners()
+	require.NoError(t, err)
+	require.Len(t, miners, 2)
+	require.Equal(t, "lotus", miners[0].Agent.Name)
+	require.Equal(t, pib(5).String(), miners[0].Power.QualityAdjPower.String())
+
+	venus, err := mainnet.GetVenusStatic()
+	require.NoError(t, err)
+	require.Equal(t, 1, venus.Count)
+	venus, err = calib.GetVenusStatic()
+	require.NoError(t, err)
+	require.Equal(t, 0, venus.Count)
+
+	_, err = calib.GetMinerDetail(abi.ActorID(1002))
+	require.NoError(t, err)
+	_, err = mainnet.GetMinerDetail(abi.ActorID(1002))
+	require.Equal(t, ErrMinerNotFound, err)
+
+	require.Equal(t, DefaultNetwork, mainnet.ForNetwork("").Network())
+}
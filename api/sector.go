@@ -0,0 +1,36 @@
+package api
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// update Miner SectorPower
+func (a *Api) UpdateMinerSectorPower(sectors *SectorPower) error {
+	a.stamp(&sectors.Network)
+	err := a.saveMiner(sectors.MinerID, sectors.Network)
+	if err != nil {
+		return err
+	}
+	err = a.db.Create(sectors).Error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// getSectorPowers return the latest measured sector power of miners which have been deep crawled
+func (a *Api) getSectorPowers(ids ...abi.ActorID) (map[abi.ActorID]*SectorPower, error) {
+	ids = unique(ids)
+
+	var sectors []SectorPower
+	err := a.scope().Select("miner_id, sectors, cc_bytes, deal_bytes, verified_bytes, updated_at,  max(updated_at) as max_updated_at").Where("miner_id in ?", ids).Group("miner_id").Table("sector_powers").Find(&sectors).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[abi.ActorID]*SectorPower, len(sectors))
+	for i := range sectors {
+		ret[sectors[i].MinerID] = &sectors[i]
+	}
+	return ret, nil
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"database/sql/driver"
 	"errors"
+	"gorm.io/gorm"
 	"strings"
 	"time"
 
@@ -13,6 +14,9 @@ import (
 type Power big.Int
 
 func (p *Power) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
 	return p.String(), nil
 }
 
@@ -45,6 +49,9 @@ func (p *Power) UnmarshalJSON(b []byte) error {
 type Multiaddrs []string
 
 func (m *Multiaddrs) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
 	s := strings.Join(*m, ",")
 	return s, nil
 }
@@ -61,15 +68,75 @@ func (m *Multiaddrs) Scan(src interface{}) error {
 	return nil
 }
 
+type Protocols []string
+
+func (p *Protocols) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	s := strings.Join(*p, ",")
+	return s, nil
+}
+
+func (p *Protocols) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		s := string(src)
+		strs := strings.Split(s, ",")
+		*p = append(*p, strs...)
+	default:
+		return errors.New("invalid protocols")
+	}
+	return nil
+}
+
+type Addresses []string
+
+func (a *Addresses) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	s := strings.Join(*a, ",")
+	return s, nil
+}
+
+func (a *Addresses) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		s := string(src)
+		strs := strings.Split(s, ",")
+		*a = append(*a, strs...)
+	default:
+		return errors.New("invalid addresses")
+	}
+	return nil
+}
+
 type Miner struct {
-	ID    abi.ActorID `gorm:"primaryKey"`
-	Power *PowerInfo  `gorm:"-"`
-	Peer  *PeerInfo   `gorm:"-"`
-	Agent *AgentInfo  `gorm:"-"`
+	ID       abi.ActorID   `gorm:"primaryKey"`
+	Power    *PowerInfo    `gorm:"-"`
+	Peer     *PeerInfo     `gorm:"-"`
+	Agent    *AgentInfo    `gorm:"-"`
+	Identify *IdentifyInfo `gorm:"-"`
+	Probe    *ProbeResult  `gorm:"-"`
+	Geo      *GeoInfo      `gorm:"-"`
+	Meta     *MinerMeta    `gorm:"-"`
+	Sectors  *SectorPower  `gorm:"-"`
+	Faults   *FaultInfo    `gorm:"-"`
+	Balance  *BalanceInfo  `gorm:"-"`
+	// expiration schedule read by the crawler, not loaded with the other records
+	Expirations []SectorExpiration `gorm:"-"`
+}
+
+// MinerNetwork record the networks a miner has been seen on, as actor ids are reused across networks
+type MinerNetwork struct {
+	MinerID abi.ActorID `gorm:"primaryKey"`
+	Network string      `gorm:"primaryKey"`
 }
 
 type PeerInfo struct {
 	MinerID    abi.ActorID `gorm:"index"`
+	Network    string      `gorm:"index"`
 	PeerId     string
 	Multiaddrs *Multiaddrs
 	UpdatedAt  time.Time
@@ -77,16 +144,205 @@ type PeerInfo struct {
 
 type PowerInfo struct {
 	MinerID         abi.ActorID `gorm:"index"`
+	Network         string      `gorm:"index"`
 	RawBytePower    *Power
 	QualityAdjPower *Power
-	UpdatedAt       time.Time
+	// the miner doesn't reach the min power, so the network doesn't count its power
+	BelowMin  bool
+	UpdatedAt time.Time
 }
 
 type AgentInfo struct {
+	MinerID abi.ActorID `gorm:"index"`
+	Network string      `gorm:"index"`
+	Name    string
+
+	// parsed from Name, see ParseAgent
+	Impl         string
+	Component    string
+	Version      string
+	BuildNetwork string
+	GitCommit    string
+
+	UpdatedAt time.Time
+}
+
+// IdentifyInfo holds what the libp2p identify exchange told us about the miner's peer
+type IdentifyInfo struct {
+	MinerID         abi.ActorID `gorm:"index"`
+	Network         string      `gorm:"index"`
+	PeerId          string
+	ProtocolVersion string
+	Protocols       *Protocols
+	ListenAddrs     *Multiaddrs
+	UpdatedAt       time.Time
+}
+
+const (
+	ProbeSuccess     = "success"
+	ProbeNoPeerInfo  = "no_peer_info"
+	ProbeDecodeError = "decode_error"
+	ProbeRefused     = "connection_refused"
+	ProbeTimeout     = "timeout"
+	ProbeDialError   = "dial_error"
+	ProbeNoAgent     = "no_agent"
+)
+
+// ProbeResult is one attempt to reach the peer of a miner
+type ProbeResult struct {
+	MinerID abi.ActorID `gorm:"index"`
+	Network string      `gorm:"index"`
+	Outcome string
+	Error   string
+	// milliseconds spent on connect and identify
+	Latency int64
+	// the address which the connection established over
+	Addr      string
+	UpdatedAt time.Time
+}
+
+const (
+	ScopePublic       = "public"
+	ScopePrivate      = "private"
+	ScopeLoopback     = "loopback"
+	ScopeUnresolvable = "unresolvable"
+)
+
+const (
+	TransportTCP          = "tcp"
+	TransportQUIC         = "quic"
+	TransportWS           = "ws"
+	TransportWebTransport = "webtransport"
+	TransportOther        = "other"
+)
+
+// AddrProbe is the result of dialing one advertised address of a miner on its own,
+// probes of one miner in the same round share the same UpdatedAt
+type AddrProbe struct {
 	MinerID   abi.ActorID `gorm:"index"`
-	Name      string
+	Network   string      `gorm:"index"`
+	Addr      string
+	Scope     string
+	Transport string
+	Reachable bool
+	// milliseconds spent on connect and identify
+	Latency   int64
+	Error     string
+	UpdatedAt time.Time
+}
+
+// GeoInfo locate a miner by the first public ip it advertised
+type GeoInfo struct {
+	MinerID abi.ActorID `gorm:"index"`
+	Network string      `gorm:"index"`
+	IP      string
+	// ISO 3166-1 country code
+	Country   string
+	Region    string
+	ASN       uint
+	ASOrg     string
+	UpdatedAt time.Time
+}
+
+// MinerMeta is the on chain info of a miner, addresses are ID addresses
+type MinerMeta struct {
+	MinerID             abi.ActorID `gorm:"index"`
+	Network             string      `gorm:"index"`
+	Owner               string
+	Worker              string
+	ControlAddresses    *Addresses
+	Beneficiary         string
+	SectorSize          abi.SectorSize
+	WindowPoStProofType abi.RegisteredPoStProof
+	UpdatedAt           time.Time
+}
+
+// SectorPower is the raw bytes of a miner measured from its active sectors by deep crawl
+type SectorPower struct {
+	MinerID abi.ActorID `gorm:"index"`
+	Network string      `gorm:"index"`
+	Sectors uint64
+	// bytes of sectors not covered by any deal
+	CCBytes *Power
+	// bytes covered by regular deals
+	DealBytes *Power
+	// bytes covered by verified deals
+	VerifiedBytes *Power
+	UpdatedAt     time.Time
+}
+
+// SectorExpiration is the active sectors of a miner scheduled to expire in one month of 30 days counted from genesis,
+// so schedules read at different heights line up, expirations of one miner in the same crawl share the same UpdatedAt,
+// and a miner without active sectors has a single one without sectors
+type SectorExpiration struct {
+	MinerID abi.ActorID `gorm:"index"`
+	Network string      `gorm:"index"`
+	// epoch the schedule is read at
+	Height abi.ChainEpoch
+	// first epoch of the month the sectors expire in, see MonthOf
+	Epoch    abi.ChainEpoch
+	Sectors  uint64
+	RawBytes *Power
+	QABytes  *Power
+	// raw bytes of sectors without any deal, the rest of RawBytes is sealed with deals
+	CCBytes   *Power
+	UpdatedAt time.Time
+}
+
+// FaultInfo is the faulty sectors of a miner, which the power is lost of, and those declared recovering
+type FaultInfo struct {
+	MinerID    abi.ActorID `gorm:"index"`
+	Network    string      `gorm:"index"`
+	Faults     uint64
+	Recoveries uint64
+	// raw bytes of faulty sectors, which are taken out of the power claim
+	FaultyBytes *Power
+	UpdatedAt   time.Time
+}
+
+// BalanceInfo is the funds of a miner actor in attoFIL
+type BalanceInfo struct {
+	MinerID abi.ActorID `gorm:"index"`
+	Network string      `gorm:"index"`
+	Balance *Power
+	// the part of balance could be withdrawn
+	Available     *Power
+	InitialPledge *Power
+	// rewards locked in the vesting table
+	LockedFunds       *Power
+	PreCommitDeposits *Power
+	FeeDebt           *Power
+	UpdatedAt         time.Time
+}
+
+// BlockInfo is a block on chain and the miner won it
+type BlockInfo struct {
+	Cid     string         `gorm:"primaryKey"`
+	Network string         `gorm:"primaryKey"`
+	Height  abi.ChainEpoch `gorm:"index"`
+	MinerID abi.ActorID    `gorm:"index"`
+	// times the miner won the election in the block, rewarded for each
+	WinCount int64
+	// block reward in attoFIL without gas, nil if the reward actor could not be read
+	Reward    *Power
+	UpdatedAt time.Time
+}
+
+// Checkpoint is the chain position of the last successful crawl of a job
+type Checkpoint struct {
+	Name    string `gorm:"primaryKey"`
+	Network string `gorm:"primaryKey"`
+	Height  abi.ChainEpoch
+	TipSet  string
+	// parent state root of the tipset, which the crawl read from
+	StateRoot string
 	UpdatedAt time.Time
 }
 
 type Api struct {
+	db *gorm.DB
+	// network the Api reads from
+	network string
+	// how statistics select miners below min power, see BelowMinExclude
+	belowMin string
 }
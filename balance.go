@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	sapi "static-power/api"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// minerFunds is the part of miner actor state read by StateReadState, the fields are named the same since actors v2
+type minerFunds struct {
+	InitialPledge     abi.TokenAmount
+	LockedFunds       abi.TokenAmount
+	PreCommitDeposits abi.TokenAmount
+	FeeDebt           abi.TokenAmount
+}
+
+// measureBalance read the balance of miner and the funds locked as collateral
+func measureBalance(ctx context.Context, node ChainNode, tsk types.TipSetKey, maddr address.Address, aid abi.ActorID) (*sapi.BalanceInfo, error) {
+	st, err := node.StateReadState(ctx, maddr, tsk)
+	if err != nil {
+		return nil, fmt.Errorf("read state: %w", err)
+	}
+	// the state is decoded as a map over json rpc, convert it by the field names
+	data, err := json.Marshal(st.State)
+	if err != nil {
+		return nil, fmt.Errorf("marshal state: %w", err)
+	}
+	var funds minerFunds
+	err = json.Unmarshal(data, &funds)
+	if err != nil {
+		return nil, fmt.Errorf("decode funds in state: %w", err)
+	}
+
+	available, err := node.StateMinerAvailableBalance(ctx, maddr, tsk)
+	if err != nil {
+		return nil, fmt.Errorf("get available balance: %w", err)
+	}
+
+	power := func(v abi.TokenAmount) *sapi.Power {
+		if v.Int == nil {
+			v = big.Zero()
+		}
+		p := sapi.Power(v)
+		return &p
+	}
+	return &sapi.BalanceInfo{
+		MinerID:           aid,
+		Balance:           power(st.Balance),
+		Available:         power(available),
+		InitialPledge:     power(funds.InitialPledge),
+		LockedFunds:       power(funds.LockedFunds),
+		PreCommitDeposits: power(funds.PreCommitDeposits),
+		FeeDebt:           power(funds.FeeDebt),
+	}, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	sapi "static-power/api"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/builtin"
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/reward"
+	"github.com/filecoin-project/lotus/chain/types"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/urfave/cli/v2"
+)
+
+// name of the checkpoint kept by update-blocks
+const updateBlocksCheckpoint = "update-blocks"
+
+var updateBlocksCmd = &cli.Command{
+	Name:  "update-blocks",
+	Usage: "record the blocks won by miners and their rewards in an epoch range",
+	Flags: append(append(append(nodeFlags, recorderFlags...), outputFlags...),
+		&cli.Int64Flag{
+			Name:  "from",
+			Usage: "first epoch to walk, the one after the last run by default, or a day before --to for the first run",
+		},
+		&cli.Int64Flag{
+			Name:  "to",
+			Usage: "last epoch to walk, the head by default",
+		},
+	),
+	Action: func(c *cli.Context) error {
+		ctx := c.Context
+		node, closer, err := connectNode(c)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		r, err := openNodeRecorder(c, node)
+		if err != nil {
+			return err
+		}
+		defer closeRecorder(r)
+
+		head, err := node.ChainHead(ctx)
+		if err != nil {
+			return fmt.Errorf("get chain head: %w", err)
+		}
+
+		to := head.Height()
+		if c.IsSet("to") {
+			to = abi.ChainEpoch(c.Int64("to"))
+		}
+		from := to - builtin.EpochsInDay + 1
+		if c.IsSet("from") {
+			from = abi.ChainEpoch(c.Int64("from"))
+		} else if cp, err := r.GetCheckpoint(updateBlocksCheckpoint); err == nil {
+			from = cp.Height + 1
+		} else if !errors.Is(err, sapi.ErrCheckpointNotFound) {
+			log.Printf("get checkpoint %s: %s", updateBlocksCheckpoint, err)
+		}
+		if from > to {
+			log.Printf("no epochs to walk from %d to %d", from, to)
+			return nil
+		}
+
+		last, err := crawlBlocks(ctx, node, r, from, to, head.Key())
+		if err != nil {
+			return err
+		}
+		err = r.UpdateCheckpoint(checkpointOf(updateBlocksCheckpoint, last))
+		if err != nil {
+			log.Printf("update checkpoint: %s", err)
+		}
+		return nil
+	},
+}
+
+// crawlBlocks record the blocks of tipsets from epoch from to to on the chain of tsk, null rounds are skipped,
+// return the tipset at to, or the one before it if to is a null round
+func crawlBlocks(ctx context.Context, node ChainNode, r recorder, from, to abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	var last *types.TipSet
+	count := 0
+	for h := from; h <= to; h++ {
+		ts, err := node.ChainGetTipSetByHeight(ctx, h, tsk)
+		if err != nil {
+			return nil, fmt.Errorf("get tipset at %d: %w", h, err)
+		}
+		last = ts
+		if ts.Height() != h {
+			continue
+		}
+
+		perWin, err := rewardPerWin(ctx, node, ts.Key())
+		if err != nil {
+			log.Printf("get block reward at %d: %s", h, err)
+		}
+		blocks, err := blockInfos(ts, perWin)
+		if err != nil {
+			return nil, err
+		}
+		err = r.UpdateBlocks(blocks)
+		if err != nil {
+			return nil, fmt.Errorf("update blocks at %d: %w", h, err)
+		}
+		count += len(blocks)
+	}
+	log.Printf("update (%d) blocks from %d to %d", count, from, to)
+	return last, nil
+}
+
+// rewardPerWin is the block reward of one win in tipset tsk, which is paid out of the reward
+// actor state the tipset is executed on
+func rewardPerWin(ctx context.Context, node ChainNode, tsk types.TipSetKey) (abi.TokenAmount, error) {
+	act, err := node.StateGetActor(ctx, reward.Address, tsk)
+	if err != nil {
+		return big.Int{}, fmt.Errorf("get reward actor: %w", err)
+	}
+	store := adt.WrapStore(ctx, cbor.NewCborStore(blockstore.NewAPIBlockstore(chainReader{node})))
+	st, err := reward.Load(store, act)
+	if err != nil {
+		return big.Int{}, fmt.Errorf("load reward actor state: %w", err)
+	}
+	epochReward, err := st.ThisEpochReward()
+	if err != nil {
+		return big.Int{}, fmt.Errorf("get epoch reward: %w", err)
+	}
+	return big.Div(epochReward, big.NewInt(builtin.ExpectedLeadersPerEpoch)), nil
+}
+
+// blockInfos convert the blocks of ts, perWin is the reward of one win, rewards are left nil if unknown
+func blockInfos(ts *types.TipSet, perWin abi.TokenAmount) ([]sapi.BlockInfo, error) {
+	blocks := make([]sapi.BlockInfo, 0, len(ts.Blocks()))
+	for _, b := range ts.Blocks() {
+		id, err := address.IDFromAddress(b.Miner)
+		if err != nil {
+			return nil, fmt.Errorf("miner of block %s: %w", b.Cid(), err)
+		}
+		var winCount int64
+		if b.ElectionProof != nil {
+			winCount = b.ElectionProof.WinCount
+		}
+
+		info := sapi.BlockInfo{
+			Cid:      b.Cid().String(),
+			Height:   b.Height,
+			MinerID:  abi.ActorID(id),
+			WinCount: winCount,
+		}
+		if perWin.Int != nil {
+			r := sapi.Power(big.Mul(perWin, big.NewInt(winCount)))
+			info.Reward = &r
+		}
+		blocks = append(blocks, info)
+	}
+	return blocks, nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
+	initact "github.com/filecoin-project/lotus/chain/actors/builtin/init"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/power"
+	"github.com/filecoin-project/lotus/chain/state"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	carbs "github.com/ipld/go-car/v2/blockstore"
+	"github.com/libp2p/go-libp2p/core/peer"
+)
+
+var errOffline = errors.New("not available from a state snapshot")
+
+// carNode reads one state tree from a chain export, tipset keys are ignored,
+// blocks are read from the file through an index so the export is not loaded into memory
+type carNode struct {
+	bs    *carbs.ReadOnly
+	store adt.Store
+	tree  *state.StateTree
+	// the tree caches the nodes and addresses it resolves without locking, and is read by concurrent crawls
+	treeLk sync.Mutex
+	// height of the head tipset whose parent state is read, 0 if the state root is given
+	height abi.ChainEpoch
+}
+
+var _ ChainNode = (*carNode)(nil)
+
+// openCarNode open the chain export at path, the state root is taken from the parent state of the head
+// tipset of the export if not given
+func openCarNode(ctx context.Context, path string, stateRoot string) (*carNode, error) {
+	bs, err := carbs.OpenReadOnly(path)
+	if err != nil {
+		return nil, fmt.Errorf("open car %s: %w", path, err)
+	}
+	cst := cbor.NewCborStore(bs)
+
+	var root cid.Cid
+	var height abi.ChainEpoch
+	if stateRoot != "" {
+		root, err = cid.Decode(stateRoot)
+		if err != nil {
+			bs.Close()
+			return nil, fmt.Errorf("decode state root %s: %w", stateRoot, err)
+		}
+	} else {
+		root, height, err = headStateRoot(ctx, bs, cst)
+		if err != nil {
+			bs.Close()
+			return nil, err
+		}
+	}
+
+	tree, err := state.LoadStateTree(cst, root)
+	if err != nil {
+		bs.Close()
+		return nil, fmt.Errorf("load state tree %s: %w", root, err)
+	}
+	return &carNode{
+		bs:     bs,
+		store:  adt.WrapStore(ctx, cst),
+		tree:   tree,
+		height: height,
+	}, nil
+}
+
+// headStateRoot read the parent state of the first block of the head tipset, which is the root of a chain export,
+// and the height of the head
+func headStateRoot(ctx context.Context, bs *carbs.ReadOnly, cst cbor.IpldStore) (cid.Cid, abi.ChainEpoch, error) {
+	roots, err := bs.Roots()
+	if err != nil {
+		return cid.Undef, 0, fmt.Errorf("read car roots: %w", err)
+	}
+	if len(roots) == 0 {
+		return cid.Undef, 0, errors.New("car has no root, state root is required")
+	}
+	var header types.BlockHeader
+	err = cst.Get(ctx, roots[0], &header)
+	if err != nil {
+		return cid.Undef, 0, fmt.Errorf("car root is not a block header, state root is required: %w", err)
+	}
+	return header.ParentStateRoot, header.Height, nil
+}
+
+func (n *carNode) Close() error {
+	return n.bs.Close()
+}
+
+func (n *carNode) ChainHead(ctx context.Context) (*types.TipSet, error) {
+	return nil, errOffline
+}
+
+func (n *carNode) ChainNotify(ctx context.Context) (<-chan []*api.HeadChange, error) {
+	return nil, errOffline
+}
+
+func (n *carNode) ChainGetTipSetByHeight(ctx context.Context, height abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	return nil, errOffline
+}
+
+func (n *carNode) ChainReadObj(ctx context.Context, c cid.Cid) ([]byte, error) {
+	blk, err := n.bs.Get(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return blk.RawData(), nil
+}
+
+func (n *carNode) ChainHasObj(ctx context.Context, c cid.Cid) (bool, error) {
+	return n.bs.Has(ctx, c)
+}
+
+func (n *carNode) StateNetworkName(ctx context.Context) (dtypes.NetworkName, error) {
+	act, err := n.getActor(initact.Address)
+	if err != nil {
+		return "", fmt.Errorf("get init actor: %w", err)
+	}
+	st, err := initact.Load(n.store, act)
+	if err != nil {
+		return "", fmt.Errorf("load init actor state: %w", err)
+	}
+	return st.NetworkName()
+}
+
+func (n *carNode) StateGetActor(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*types.Actor, error) {
+	return n.getActor(actor)
+}
+
+func (n *carNode) StateListMiners(ctx context.Context, tsk types.TipSetKey) ([]address.Address, error) {
+	st, err := n.power()
+	if err != nil {
+		return nil, err
+	}
+	return st.ListAllMiners()
+}
+
+func (n *carNode) StateLookupID(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error) {
+	n.treeLk.Lock()
+	defer n.treeLk.Unlock()
+	return n.tree.LookupID(addr)
+}
+
+func (n *carNode) StateMinerPower(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (*api.MinerPower, error) {
+	st, err := n.power()
+	if err != nil {
+		return nil, err
+	}
+	claim, _, err := st.MinerPower(maddr)
+	if err != nil {
+		return nil, fmt.Errorf("get power of %s: %w", maddr, err)
+	}
+	total, err := st.TotalPower()
+	if err != nil {
+		return nil, fmt.Errorf("get total power: %w", err)
+	}
+	hasMinPower, err := st.MinerNominalPowerMeetsConsensusMinimum(maddr)
+	if err != nil {
+		return nil, fmt.Errorf("check min power of %s: %w", maddr, err)
+	}
+	return &api.MinerPower{
+		MinerPower:  claim,
+		TotalPower:  total,
+		HasMinPower: hasMinPower,
+	}, nil
+}
+
+// StateMinerInfo convert the info in miner state the same way as lotus
+func (n *carNode) StateMinerInfo(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (api.MinerInfo, error) {
+	mas, err := n.miner(maddr)
+	if err != nil {
+		return api.MinerInfo{}, err
+	}
+	info, err := mas.Info()
+	if err != nil {
+		return api.MinerInfo{}, fmt.Errorf("get info of miner %s: %w", maddr, err)
+	}
+
+	var pid *peer.ID
+	if peerID, err := peer.IDFromBytes(info.PeerId); err == nil {
+		pid = &peerID
+	}
+	ret := api.MinerInfo{
+		Owner:                      info.Owner,
+		Worker:                     info.Worker,
+		ControlAddresses:           info.ControlAddresses,
+		NewWorker:                  address.Undef,
+		WorkerChangeEpoch:          -1,
+		PeerId:                     pid,
+		Multiaddrs:                 info.Multiaddrs,
+		WindowPoStProofType:        info.WindowPoStProofType,
+		SectorSize:                 info.SectorSize,
+		WindowPoStPartitionSectors: info.WindowPoStPartitionSectors,
+		ConsensusFaultElapsed:      info.ConsensusFaultElapsed,
+		Beneficiary:                info.Beneficiary,
+		BeneficiaryTerm:            &info.BeneficiaryTerm,
+		PendingBeneficiaryTerm:     info.PendingBeneficiaryTerm,
+	}
+	if info.PendingWorkerKey != nil {
+		ret.NewWorker = info.PendingWorkerKey.NewWorker
+		ret.WorkerChangeEpoch = info.PendingWorkerKey.EffectiveAt
+	}
+	return ret, nil
+}
+
+func (n *carNode) StateMinerActiveSectors(ctx context.Context, maddr address.Address, tsk types.TipSetKey) ([]*miner.SectorOnChainInfo, error) {
+	mas, err := n.miner(maddr)
+	if err != nil {
+		return nil, err
+	}
+	active, err := miner.AllPartSectors(mas, miner.Partition.ActiveSectors)
+	if err != nil {
+		return nil, fmt.Errorf("merge partition active sets of miner %s: %w", maddr, err)
+	}
+	return mas.LoadSectors(&active)
+}
+
+func (n *carNode) StateMinerFaults(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error) {
+	mas, err := n.miner(maddr)
+	if err != nil {
+		return bitfield.BitField{}, err
+	}
+	return miner.AllPartSectors(mas, miner.Partition.FaultySectors)
+}
+
+func (n *carNode) StateMinerRecoveries(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error) {
+	mas, err := n.miner(maddr)
+	if err != nil {
+		return bitfield.BitField{}, err
+	}
+	return miner.AllPartSectors(mas, miner.Partition.RecoveringSectors)
+}
+
+func (n *carNode) StateMinerAvailableBalance(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (types.BigInt, error) {
+	act, err := n.getActor(maddr)
+	if err != nil {
+		return types.BigInt{}, fmt.Errorf("get miner actor %s: %w", maddr, err)
+	}
+	mas, err := miner.Load(n.store, act)
+	if err != nil {
+		return types.BigInt{}, fmt.Errorf("load state of miner %s: %w", maddr, err)
+	}
+	return mas.AvailableBalance(act.Balance)
+}
+
+// StateReadState only read the funds of miners offline, in the same fields as the miner state
+func (n *carNode) StateReadState(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*api.ActorState, error) {
+	act, err := n.getActor(actor)
+	if err != nil {
+		return nil, fmt.Errorf("get actor %s: %w", actor, err)
+	}
+	mas, err := miner.Load(n.store, act)
+	if err != nil {
+		return nil, fmt.Errorf("load state of miner %s: %w", actor, err)
+	}
+	locked, err := mas.LockedFunds()
+	if err != nil {
+		return nil, fmt.Errorf("get locked funds of miner %s: %w", actor, err)
+	}
+	debt, err := mas.FeeDebt()
+	if err != nil {
+		return nil, fmt.Errorf("get fee debt of miner %s: %w", actor, err)
+	}
+	return &api.ActorState{
+		Balance: act.Balance,
+		Code:    act.Code,
+		State: minerFunds{
+			InitialPledge:     locked.InitialPledgeRequirement,
+			LockedFunds:       locked.VestingFunds,
+			PreCommitDeposits: locked.PreCommitDeposits,
+			FeeDebt:           debt,
+		},
+	}, nil
+}
+
+func (n *carNode) StateChangedActors(ctx context.Context, from, to cid.Cid) (map[string]types.Actor, error) {
+	return nil, errOffline
+}
+
+func (n *carNode) getActor(addr address.Address) (*types.Actor, error) {
+	n.treeLk.Lock()
+	defer n.treeLk.Unlock()
+	return n.tree.GetActor(addr)
+}
+
+func (n *carNode) power() (power.State, error) {
+	act, err := n.getActor(power.Address)
+	if err != nil {
+		return nil, fmt.Errorf("get power actor: %w", err)
+	}
+	st, err := power.Load(n.store, act)
+	if err != nil {
+		return nil, fmt.Errorf("load power actor state: %w", err)
+	}
+	return st, nil
+}
+
+func (n *carNode) miner(maddr address.Address) (miner.State, error) {
+	act, err := n.getActor(maddr)
+	if err != nil {
+		return nil, fmt.Errorf("get miner actor %s: %w", maddr, err)
+	}
+	mas, err := miner.Load(n.store, act)
+	if err != nil {
+		return nil, fmt.Errorf("load state of miner %s: %w", maddr, err)
+	}
+	return mas, nil
+}
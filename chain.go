@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/urfave/cli/v2"
+)
+
+// ChainNode is the chain source of the crawler, only the calls it needs
+type ChainNode interface {
+	ChainHead(ctx context.Context) (*types.TipSet, error)
+	ChainNotify(ctx context.Context) (<-chan []*api.HeadChange, error)
+	ChainGetTipSetByHeight(ctx context.Context, height abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error)
+	ChainReadObj(ctx context.Context, c cid.Cid) ([]byte, error)
+	ChainHasObj(ctx context.Context, c cid.Cid) (bool, error)
+	StateNetworkName(ctx context.Context) (dtypes.NetworkName, error)
+	StateGetActor(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*types.Actor, error)
+	StateListMiners(ctx context.Context, tsk types.TipSetKey) ([]address.Address, error)
+	StateLookupID(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)
+	StateMinerPower(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (*api.MinerPower, error)
+	StateMinerInfo(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (api.MinerInfo, error)
+	StateMinerActiveSectors(ctx context.Context, maddr address.Address, tsk types.TipSetKey) ([]*miner.SectorOnChainInfo, error)
+	StateMinerFaults(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error)
+	StateMinerAvailableBalance(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (types.BigInt, error)
+	StateReadState(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*api.ActorState, error)
+	StateMinerRecoveries(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error)
+	StateChangedActors(ctx context.Context, from, to cid.Cid) (map[string]types.Actor, error)
+}
+
+var _ ChainNode = (api.FullNode)(nil)
+var _ ChainNode = (*venusNode)(nil)
+
+const (
+	NodeLotus = "lotus"
+	NodeVenus = "venus"
+)
+
+var nodeFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "node",
+		Usage: "entry point for a filecoin node, url like ws://127.0.0.1:1234/rpc/v1 or multiaddr like /ip4/127.0.0.1/tcp/3453",
+	},
+	&cli.StringFlag{
+		Name:  "token",
+		Usage: "token for a filecoin node",
+	},
+	&cli.StringFlag{
+		Name:  "node-type",
+		Usage: "implementation of the node, lotus or venus (venus daemon or sophon-gateway)",
+		Value: NodeLotus,
+	},
+}
+
+// connectNode connect the node given by flags
+func connectNode(c *cli.Context) (ChainNode, jsonrpc.ClientCloser, error) {
+	url := c.String("node")
+	token := c.String("token")
+	if url == "" {
+		return nil, nil, errors.New("node url is required")
+	}
+	if token == "" {
+		return nil, nil, errors.New("node token is required")
+	}
+	return NewChainNode(c.String("node-type"), url, token)
+}
+
+// NewChainNode connect a node of nodeType
+func NewChainNode(nodeType string, endpoint string, token string) (ChainNode, jsonrpc.ClientCloser, error) {
+	endpoint, err := rpcEndpoint(endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch nodeType {
+	case NodeLotus:
+		return NewRpcClient(endpoint, &token)
+	case NodeVenus:
+		return newVenusClient(endpoint, token)
+	default:
+		return nil, nil, fmt.Errorf("unknown node type %s", nodeType)
+	}
+}
+
+// rpcEndpoint turn a multiaddr, as written in the api file of lotus and venus, into the url of rpc v1
+func rpcEndpoint(endpoint string) (string, error) {
+	if !strings.HasPrefix(endpoint, "/") {
+		return endpoint, nil
+	}
+	maddr, err := multiaddr.NewMultiaddr(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("parse node multiaddr %s: %w", endpoint, err)
+	}
+	addr, err := manet.ToNetAddr(maddr)
+	if err != nil {
+		return "", fmt.Errorf("parse node multiaddr %s: %w", endpoint, err)
+	}
+	return "ws://" + addr.String() + "/rpc/v1", nil
+}
+
+// venusNode binds only the methods the crawler calls, so that gateways serving part of the full node api,
+// like sophon-gateway, could be used, and values are decoded the same as lotus as venus keeps the json format
+type venusNode struct {
+	Internal struct {
+		ChainHead                  func(ctx context.Context) (*types.TipSet, error)                                                          `perm:"read"`
+		ChainNotify                func(ctx context.Context) (<-chan []*api.HeadChange, error)                                               `perm:"read"`
+		ChainGetTipSetByHeight     func(ctx context.Context, height abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error)              `perm:"read"`
+		ChainReadObj               func(ctx context.Context, c cid.Cid) ([]byte, error)                                                      `perm:"read"`
+		ChainHasObj                func(ctx context.Context, c cid.Cid) (bool, error)                                                        `perm:"read"`
+		StateNetworkName           func(ctx context.Context) (dtypes.NetworkName, error)                                                     `perm:"read"`
+		StateGetActor              func(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*types.Actor, error)               `perm:"read"`
+		StateListMiners            func(ctx context.Context, tsk types.TipSetKey) ([]address.Address, error)                                 `perm:"read"`
+		StateLookupID              func(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error)             `perm:"read"`
+		StateMinerPower            func(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (*api.MinerPower, error)            `perm:"read"`
+		StateMinerInfo             func(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (api.MinerInfo, error)              `perm:"read"`
+		StateMinerActiveSectors    func(ctx context.Context, maddr address.Address, tsk types.TipSetKey) ([]*miner.SectorOnChainInfo, error) `perm:"read"`
+		StateMinerFaults           func(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error)          `perm:"read"`
+		StateMinerAvailableBalance func(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (types.BigInt, error)               `perm:"read"`
+		StateReadState             func(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*api.ActorState, error)            `perm:"read"`
+		StateMinerRecoveries       func(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error)          `perm:"read"`
+		StateChangedActors         func(ctx context.Context, from, to cid.Cid) (map[string]types.Actor, error)                               `perm:"read"`
+	}
+}
+
+func newVenusClient(endpoint string, token string) (*venusNode, jsonrpc.ClientCloser, error) {
+	// venus and sophon-auth take the token in the same header as lotus
+	requestHeader := http.Header{}
+	requestHeader.Add("Authorization", "Bearer "+token)
+
+	var res venusNode
+	closer, err := jsonrpc.NewMergeClient(context.Background(), endpoint, "Filecoin", []interface{}{&res.Internal}, requestHeader)
+	return &res, closer, err
+}
+
+func (n *venusNode) ChainHead(ctx context.Context) (*types.TipSet, error) {
+	return n.Internal.ChainHead(ctx)
+}
+
+func (n *venusNode) ChainNotify(ctx context.Context) (<-chan []*api.HeadChange, error) {
+	return n.Internal.ChainNotify(ctx)
+}
+
+func (n *venusNode) ChainGetTipSetByHeight(ctx context.Context, height abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	return n.Internal.ChainGetTipSetByHeight(ctx, height, tsk)
+}
+
+func (n *venusNode) ChainReadObj(ctx context.Context, c cid.Cid) ([]byte, error) {
+	return n.Internal.ChainReadObj(ctx, c)
+}
+
+func (n *venusNode) ChainHasObj(ctx context.Context, c cid.Cid) (bool, error) {
+	return n.Internal.ChainHasObj(ctx, c)
+}
+
+func (n *venusNode) StateNetworkName(ctx context.Context) (dtypes.NetworkName, error) {
+	return n.Internal.StateNetworkName(ctx)
+}
+
+func (n *venusNode) StateGetActor(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*types.Actor, error) {
+	return n.Internal.StateGetActor(ctx, actor, tsk)
+}
+
+func (n *venusNode) StateListMiners(ctx context.Context, tsk types.TipSetKey) ([]address.Address, error) {
+	return n.Internal.StateListMiners(ctx, tsk)
+}
+
+func (n *venusNode) StateLookupID(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error) {
+	return n.Internal.StateLookupID(ctx, addr, tsk)
+}
+
+func (n *venusNode) StateMinerPower(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (*api.MinerPower, error) {
+	return n.Internal.StateMinerPower(ctx, maddr, tsk)
+}
+
+func (n *venusNode) StateMinerInfo(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (api.MinerInfo, error) {
+	return n.Internal.StateMinerInfo(ctx, maddr, tsk)
+}
+
+func (n *venusNode) StateMinerActiveSectors(ctx context.Context, maddr address.Address, tsk types.TipSetKey) ([]*miner.SectorOnChainInfo, error) {
+	return n.Internal.StateMinerActiveSectors(ctx, maddr, tsk)
+}
+
+func (n *venusNode) StateMinerFaults(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error) {
+	return n.Internal.StateMinerFaults(ctx, maddr, tsk)
+}
+
+func (n *venusNode) StateMinerRecoveries(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error) {
+	return n.Internal.StateMinerRecoveries(ctx, maddr, tsk)
+}
+
+func (n *venusNode) StateMinerAvailableBalance(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (types.BigInt, error) {
+	return n.Internal.StateMinerAvailableBalance(ctx, maddr, tsk)
+}
+
+func (n *venusNode) StateReadState(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*api.ActorState, error) {
+	return n.Internal.StateReadState(ctx, actor, tsk)
+}
+
+func (n *venusNode) StateChangedActors(ctx context.Context, from, to cid.Cid) (map[string]types.Actor, error) {
+	return n.Internal.StateChangedActors(ctx, from, to)
+}
+
+// chainReader let a blockstore read objects from node, the crawler never writes
+type chainReader struct {
+	ChainNode
+}
+
+func (chainReader) ChainPutObj(context.Context, blocks.Block) error {
+	return errors.New("chain node is read only for the crawler")
+}
+
+// cachedReader is a chainReader keeping the objects read, state walks reading the same nodes again and again,
+// like looking every miner up in the claims HAMT from its root, call the node once for each node
+type cachedReader struct {
+	chainReader
+	lk    sync.Mutex
+	cache map[cid.Cid][]byte
+}
+
+func newCachedReader(node ChainNode) *cachedReader {
+	return &cachedReader{
+		chainReader: chainReader{node},
+		cache:       make(map[cid.Cid][]byte),
+	}
+}
+
+func (r *cachedReader) ChainReadObj(ctx context.Context, c cid.Cid) ([]byte, error) {
+	r.lk.Lock()
+	data, ok := r.cache[c]
+	r.lk.Unlock()
+	if ok {
+		return data, nil
+	}
+
+	data, err := r.chainReader.ChainReadObj(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	r.lk.Lock()
+	r.cache[c] = data
+	r.lk.Unlock()
+	return data, nil
+}
+
+func (r *cachedReader) ChainHasObj(ctx context.Context, c cid.Cid) (bool, error) {
+	r.lk.Lock()
+	_, ok := r.cache[c]
+	r.lk.Unlock()
+	if ok {
+		return true, nil
+	}
+	return r.chainReader.ChainHasObj(ctx, c)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	sapi "static-power/api"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+// name of the checkpoint kept by update-peer
+const updatePeerCheckpoint = "update-peer"
+
+func checkpointOf(name string, ts *types.TipSet) *sapi.Checkpoint {
+	return &sapi.Checkpoint{
+		Name:      name,
+		Height:    ts.Height(),
+		TipSet:    ts.Key().String(),
+		StateRoot: ts.ParentState().String(),
+	}
+}
+
+// crawlAndCheckpoint crawl miners at head by opts and record them, the checkpoint name advances to head only if
+// every miner is crawled and recorded, otherwise the next run diffs from the old one and crawls the failed miners
+// again, return whether every miner is recorded
+func crawlAndCheckpoint(ctx context.Context, node ChainNode, r recorder, name string, head *types.TipSet, opts crawlOptions) (bool, error) {
+	var failures int64
+	opts.failures = &failures
+	opts.height = head.Height()
+	miners, err := crawlMiners(ctx, node, head.Key(), opts)
+	if err != nil {
+		return false, fmt.Errorf("crawl miners at %d: %w", head.Height(), err)
+	}
+	failed := postMinerInfos(r, miners)
+	log.Printf("update power at %d, %d records", head.Height(), len(miners)-failed)
+
+	if failures > 0 || failed > 0 {
+		log.Printf("keep checkpoint %s, %d failures in crawl, %d miners failed to record", name, failures, failed)
+		return false, nil
+	}
+	err = r.UpdateCheckpoint(checkpointOf(name, head))
+	if err != nil {
+		log.Printf("update checkpoint: %s", err)
+	}
+	return true, nil
+}
+
+// changedSinceCheckpoint return the actors changed between the checkpoint of job name and ts,
+// an error means the diff is not available and all miners should be crawled
+func changedSinceCheckpoint(ctx context.Context, node ChainNode, r recorder, name string, ts *types.TipSet) (map[address.Address]struct{}, error) {
+	cp, err := r.GetCheckpoint(name)
+	if err != nil {
+		return nil, fmt.Errorf("get checkpoint %s: %w", name, err)
+	}
+	from, err := cid.Decode(cp.StateRoot)
+	if err != nil {
+		return nil, fmt.Errorf("decode state root of checkpoint %s: %w", name, err)
+	}
+	return changedActors(ctx, node, from, ts.ParentState())
+}
+
+// changedActors diff two state trees, return the ID addresses of actors changed
+func changedActors(ctx context.Context, node ChainNode, from, to cid.Cid) (map[address.Address]struct{}, error) {
+	changed, err := node.StateChangedActors(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("diff state %s to %s: %w", from, to, err)
+	}
+
+	ret := make(map[address.Address]struct{}, len(changed))
+	for k := range changed {
+		addr, err := address.NewFromString(k)
+		if err != nil {
+			return nil, fmt.Errorf("parse changed actor %s: %w", k, err)
+		}
+		ret[addr] = struct{}{}
+	}
+	return ret, nil
+}
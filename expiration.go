@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sort"
+	sapi "static-power/api"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/actors/builtin"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+)
+
+// scheduleExpirations sum the power of active sectors of a miner by the month they expire in, see sapi.MonthOf,
+// so the power lost to sectors not extended could be forecast, a miner without active sectors gets an empty one
+// in the month of height, so that it replaces the older schedule
+func scheduleExpirations(aid abi.ActorID, size abi.SectorSize, height abi.ChainEpoch, sectors []*miner.SectorOnChainInfo) []sapi.SectorExpiration {
+	if len(sectors) == 0 {
+		return []sapi.SectorExpiration{{MinerID: aid, Height: height, Epoch: sapi.MonthOf(height)}}
+	}
+
+	type schedule struct {
+		sectors     uint64
+		raw, qa, cc abi.StoragePower
+	}
+	months := make(map[abi.ChainEpoch]*schedule)
+	raw := big.NewIntUnsigned(uint64(size))
+	for _, s := range sectors {
+		month := sapi.MonthOf(height)
+		if s.Expiration > height {
+			month = sapi.MonthOf(s.Expiration)
+		}
+		m, ok := months[month]
+		if !ok {
+			m = &schedule{raw: big.Zero(), qa: big.Zero(), cc: big.Zero()}
+			months[month] = m
+		}
+
+		m.sectors++
+		m.raw = big.Add(m.raw, raw)
+		m.qa = big.Add(m.qa, builtin.QAPowerForWeight(size, s.Expiration-s.Activation, s.DealWeight, s.VerifiedDealWeight))
+		if s.DealWeight.IsZero() && s.VerifiedDealWeight.IsZero() {
+			m.cc = big.Add(m.cc, raw)
+		}
+	}
+
+	ret := make([]sapi.SectorExpiration, 0, len(months))
+	for month, m := range months {
+		rbp := sapi.Power(m.raw)
+		qap := sapi.Power(m.qa)
+		ccp := sapi.Power(m.cc)
+		ret = append(ret, sapi.SectorExpiration{
+			MinerID:  aid,
+			Height:   height,
+			Epoch:    month,
+			Sectors:  m.sectors,
+			RawBytes: &rbp,
+			QABytes:  &qap,
+			CCBytes:  &ccp,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Epoch < ret[j].Epoch
+	})
+	return ret
+}
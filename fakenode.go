@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/power"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/lotus/node/modules/dtypes"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/urfave/cli/v2"
+)
+
+var fakeNodeCmd = &cli.Command{
+	Name:  "fake-node",
+	Usage: "serve the chain calls of the crawler and libp2p peers of miners from a fixture, for demos and end to end tests",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "fixture",
+			Usage:    "json file of the network and miners to serve, see testdata/miners.json",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "rpc-listen",
+			Usage: "address to serve json rpc on, the crawler connects ws://<rpc-listen>/rpc/v1 with any token",
+			Value: "127.0.0.1:1234",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		fixture, err := loadFixture(c.String("fixture"))
+		if err != nil {
+			return err
+		}
+		node, err := newFakeNode(fixture)
+		if err != nil {
+			return err
+		}
+		defer node.close()
+
+		lis, err := net.Listen("tcp", c.String("rpc-listen"))
+		if err != nil {
+			return err
+		}
+		srv := &http.Server{Handler: node.handler()}
+		go func() {
+			<-c.Context.Done()
+			srv.Close()
+		}()
+		log.Printf("serve %d miners of %s on ws://%s/rpc/v1", len(fixture.Miners), fixture.Network, lis.Addr())
+		err = srv.Serve(lis)
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	},
+}
+
+// fakeFixture is the chain served by fake-node
+type fakeFixture struct {
+	Network string
+	Height  abi.ChainEpoch
+	Miners  []fakeMiner
+	// blocks of tipsets below the head, heights without blocks are null rounds
+	Blocks []fakeBlock
+}
+
+// fakeBlock is a block mined by Miner at Height
+type fakeBlock struct {
+	Height abi.ChainEpoch
+	Miner  abi.ActorID
+	// 1 if not given
+	WinCount int64
+}
+
+type fakeMiner struct {
+	ID              abi.ActorID
+	RawBytePower    abi.StoragePower
+	QualityAdjPower abi.StoragePower
+	// miners have min power unless told
+	BelowMinPower bool
+	SectorSize    abi.SectorSize
+	// the miner itself if not given
+	Owner, Worker, Beneficiary address.Address
+	// agent of the libp2p peer started for the miner, no peer if empty
+	Agent string
+	// counts of faulty and recovering sectors, numbered from 0
+	Faults, Recoveries uint64
+	// funds of the miner actor, zero if not given, the rest of balance is available
+	Balance, InitialPledge, LockedFunds abi.TokenAmount
+	// active sectors by when they expire, no active sector if empty
+	Expirations []fakeExpiration
+}
+
+// fakeExpiration is Count sectors activated at epoch 0 and expiring at Epoch, filled with verified deals if Verified
+type fakeExpiration struct {
+	Epoch    abi.ChainEpoch
+	Count    uint64
+	Verified bool
+}
+
+func loadFixture(path string) (*fakeFixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fixture: %w", err)
+	}
+	var fixture fakeFixture
+	err = json.Unmarshal(data, &fixture)
+	if err != nil {
+		return nil, fmt.Errorf("decode fixture %s: %w", path, err)
+	}
+	if fixture.Network == "" {
+		fixture.Network = "fakenet"
+	}
+	return &fixture, nil
+}
+
+// fakeNode serves a fixture as a chain node, the calls not needed by the crawler return errors
+type fakeNode struct {
+	fixture *fakeFixture
+	head    *types.TipSet
+	miners  map[address.Address]*fakeMiner
+	peers   map[address.Address]host.Host
+	total   power.Claim
+}
+
+var _ ChainNode = (*fakeNode)(nil)
+
+var errNotServed = errors.New("not served by fake node")
+
+func newFakeNode(fixture *fakeFixture) (*fakeNode, error) {
+	n := &fakeNode{
+		fixture: fixture,
+		miners:  make(map[address.Address]*fakeMiner),
+		peers:   make(map[address.Address]host.Host),
+		total:   power.Claim{RawBytePower: big.Zero(), QualityAdjPower: big.Zero()},
+	}
+	for i := range fixture.Miners {
+		m := &fixture.Miners[i]
+		maddr, err := address.NewIDAddress(uint64(m.ID))
+		if err != nil {
+			return nil, err
+		}
+		if m.RawBytePower.Int == nil {
+			m.RawBytePower = big.Zero()
+		}
+		for _, v := range []*big.Int{&m.QualityAdjPower, &m.Balance, &m.InitialPledge, &m.LockedFunds} {
+			if v.Int == nil {
+				*v = big.Zero()
+			}
+		}
+		for _, a := range []*address.Address{&m.Owner, &m.Worker, &m.Beneficiary} {
+			if *a == address.Undef {
+				*a = maddr
+			}
+		}
+		n.miners[maddr] = m
+		n.total.RawBytePower = big.Add(n.total.RawBytePower, m.RawBytePower)
+		n.total.QualityAdjPower = big.Add(n.total.QualityAdjPower, m.QualityAdjPower)
+
+		if m.Agent == "" {
+			continue
+		}
+		h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"), libp2p.UserAgent(m.Agent))
+		if err != nil {
+			n.close()
+			return nil, fmt.Errorf("start peer of miner %d: %w", m.ID, err)
+		}
+		n.peers[maddr] = h
+	}
+
+	head, err := fakeTipSet(fixture.Height, nil)
+	if err != nil {
+		n.close()
+		return nil, err
+	}
+	n.head = head
+	return n, nil
+}
+
+// fakeTipSet build a tipset of blocks at height, of one block by f01000 if none, which only needs to be decodable
+func fakeTipSet(height abi.ChainEpoch, blocks []fakeBlock) (*types.TipSet, error) {
+	root, err := abi.CidBuilder.Sum([]byte("fake-node"))
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		blocks = []fakeBlock{{Height: height, Miner: 1000}}
+	}
+
+	headers := make([]*types.BlockHeader, 0, len(blocks))
+	for i, b := range blocks {
+		minerAddr, err := address.NewIDAddress(uint64(b.Miner))
+		if err != nil {
+			return nil, err
+		}
+		winCount := b.WinCount
+		if winCount == 0 {
+			winCount = 1
+		}
+		headers = append(headers, &types.BlockHeader{
+			Miner:                 minerAddr,
+			Ticket:                &types.Ticket{VRFProof: []byte(fmt.Sprintf("fake-node-%d", i))},
+			ElectionProof:         &types.ElectionProof{WinCount: winCount, VRFProof: []byte("fake-node")},
+			Height:                height,
+			ParentWeight:          big.Zero(),
+			ParentStateRoot:       root,
+			ParentMessageReceipts: root,
+			Messages:              root,
+			ParentBaseFee:         big.Zero(),
+		})
+	}
+	return types.NewTipSet(headers)
+}
+
+func (n *fakeNode) close() {
+	for _, h := range n.peers {
+		h.Close()
+	}
+}
+
+// handler serve the node over json rpc
+func (n *fakeNode) handler() http.Handler {
+	rpc := jsonrpc.NewServer()
+	rpc.Register("Filecoin", n)
+	mux := http.NewServeMux()
+	mux.Handle("/rpc/v1", rpc)
+	mux.Handle("/rpc/v0", rpc)
+	return mux
+}
+
+func (n *fakeNode) miner(maddr address.Address) (*fakeMiner, error) {
+	m, ok := n.miners[maddr]
+	if !ok {
+		return nil, fmt.Errorf("actor %s not found", maddr)
+	}
+	return m, nil
+}
+
+func (n *fakeNode) ChainHead(ctx context.Context) (*types.TipSet, error) {
+	return n.head, nil
+}
+
+// ChainNotify send the head as current, the fake chain never moves
+func (n *fakeNode) ChainNotify(ctx context.Context) (<-chan []*api.HeadChange, error) {
+	ch := make(chan []*api.HeadChange, 1)
+	ch <- []*api.HeadChange{{Type: hcCurrent, Val: n.head}}
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// ChainGetTipSetByHeight return the tipset of fixture blocks at height, or the one below it on null rounds as lotus
+func (n *fakeNode) ChainGetTipSetByHeight(ctx context.Context, height abi.ChainEpoch, tsk types.TipSetKey) (*types.TipSet, error) {
+	if height > n.head.Height() {
+		return nil, fmt.Errorf("looking for tipset with height greater than start point")
+	}
+	if height == n.head.Height() {
+		return n.head, nil
+	}
+	for h := height; h >= 0; h-- {
+		var blocks []fakeBlock
+		for _, b := range n.fixture.Blocks {
+			if b.Height == h {
+				blocks = append(blocks, b)
+			}
+		}
+		if len(blocks) > 0 {
+			return fakeTipSet(h, blocks)
+		}
+	}
+	return nil, fmt.Errorf("no tipset at or below height %d", height)
+}
+
+func (n *fakeNode) ChainReadObj(ctx context.Context, c cid.Cid) ([]byte, error) {
+	return nil, errNotServed
+}
+
+func (n *fakeNode) ChainHasObj(ctx context.Context, c cid.Cid) (bool, error) {
+	return false, errNotServed
+}
+
+func (n *fakeNode) StateNetworkName(ctx context.Context) (dtypes.NetworkName, error) {
+	return dtypes.NetworkName(n.fixture.Network), nil
+}
+
+// StateGetActor is not served, so the crawler queries miners one by one
+func (n *fakeNode) StateGetActor(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*types.Actor, error) {
+	return nil, errNotServed
+}
+
+func (n *fakeNode) StateListMiners(ctx context.Context, tsk types.TipSetKey) ([]address.Address, error) {
+	ret := make([]address.Address, 0, len(n.fixture.Miners))
+	for _, m := range n.fixture.Miners {
+		maddr, err := address.NewIDAddress(uint64(m.ID))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, maddr)
+	}
+	return ret, nil
+}
+
+func (n *fakeNode) StateLookupID(ctx context.Context, addr address.Address, tsk types.TipSetKey) (address.Address, error) {
+	if addr.Protocol() != address.ID {
+		return address.Undef, fmt.Errorf("actor %s not found", addr)
+	}
+	return addr, nil
+}
+
+func (n *fakeNode) StateMinerPower(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (*api.MinerPower, error) {
+	m, err := n.miner(maddr)
+	if err != nil {
+		return nil, err
+	}
+	return &api.MinerPower{
+		MinerPower:  power.Claim{RawBytePower: m.RawBytePower, QualityAdjPower: m.QualityAdjPower},
+		TotalPower:  n.total,
+		HasMinPower: !m.BelowMinPower,
+	}, nil
+}
+
+func (n *fakeNode) StateMinerInfo(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (api.MinerInfo, error) {
+	m, err := n.miner(maddr)
+	if err != nil {
+		return api.MinerInfo{}, err
+	}
+	info := api.MinerInfo{
+		Owner:             m.Owner,
+		Worker:            m.Worker,
+		NewWorker:         address.Undef,
+		WorkerChangeEpoch: -1,
+		Beneficiary:       m.Beneficiary,
+		SectorSize:        m.SectorSize,
+	}
+	if h, ok := n.peers[maddr]; ok {
+		id := h.ID()
+		info.PeerId = &id
+		for _, a := range h.Addrs() {
+			info.Multiaddrs = append(info.Multiaddrs, a.Bytes())
+		}
+	}
+	return info, nil
+}
+
+func (n *fakeNode) StateMinerActiveSectors(ctx context.Context, maddr address.Address, tsk types.TipSetKey) ([]*miner.SectorOnChainInfo, error) {
+	m, err := n.miner(maddr)
+	if err != nil {
+		return nil, err
+	}
+	sectors := []*miner.SectorOnChainInfo{}
+	for _, e := range m.Expirations {
+		verified := big.Zero()
+		if e.Verified {
+			verified = big.Mul(big.NewIntUnsigned(uint64(m.SectorSize)), big.NewInt(int64(e.Epoch)))
+		}
+		for i := uint64(0); i < e.Count; i++ {
+			sectors = append(sectors, &miner.SectorOnChainInfo{
+				SectorNumber:       abi.SectorNumber(len(sectors)),
+				Activation:         0,
+				Expiration:         e.Epoch,
+				DealWeight:         big.Zero(),
+				VerifiedDealWeight: verified,
+			})
+		}
+	}
+	return sectors, nil
+}
+
+func (n *fakeNode) StateMinerFaults(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error) {
+	m, err := n.miner(maddr)
+	if err != nil {
+		return bitfield.BitField{}, err
+	}
+	return sectorRange(m.Faults), nil
+}
+
+func (n *fakeNode) StateMinerRecoveries(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (bitfield.BitField, error) {
+	m, err := n.miner(maddr)
+	if err != nil {
+		return bitfield.BitField{}, err
+	}
+	return sectorRange(m.Recoveries), nil
+}
+
+func (n *fakeNode) StateMinerAvailableBalance(ctx context.Context, maddr address.Address, tsk types.TipSetKey) (types.BigInt, error) {
+	m, err := n.miner(maddr)
+	if err != nil {
+		return types.BigInt{}, err
+	}
+	return big.Max(big.Sub(m.Balance, big.Add(m.InitialPledge, m.LockedFunds)), big.Zero()), nil
+}
+
+// StateReadState serve the funds of miners in the fields of the miner state
+func (n *fakeNode) StateReadState(ctx context.Context, actor address.Address, tsk types.TipSetKey) (*api.ActorState, error) {
+	m, err := n.miner(actor)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ActorState{
+		Balance: m.Balance,
+		State: minerFunds{
+			InitialPledge:     m.InitialPledge,
+			LockedFunds:       m.LockedFunds,
+			PreCommitDeposits: big.Zero(),
+			FeeDebt:           big.Zero(),
+		},
+	}, nil
+}
+
+// sectorRange is the bitfield of sectors numbered 0 to count-1
+func sectorRange(count uint64) bitfield.BitField {
+	sectors := make([]uint64, count)
+	for i := range sectors {
+		sectors[i] = uint64(i)
+	}
+	return bitfield.NewFromSet(sectors)
+}
+
+func (n *fakeNode) StateChangedActors(ctx context.Context, from, to cid.Cid) (map[string]types.Actor, error) {
+	return nil, errNotServed
+}
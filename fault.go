@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	sapi "static-power/api"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// measureFaults count the faulty and recovering sectors of miner, faulty sectors are taken out of
+// the power claim, so the raw bytes lost are the faults times the sector size
+func measureFaults(ctx context.Context, node ChainNode, tsk types.TipSetKey, maddr address.Address, aid abi.ActorID, size abi.SectorSize) (*sapi.FaultInfo, error) {
+	faults, err := node.StateMinerFaults(ctx, maddr, tsk)
+	if err != nil {
+		return nil, fmt.Errorf("get faults: %w", err)
+	}
+	faultCount, err := faults.Count()
+	if err != nil {
+		return nil, fmt.Errorf("count faults: %w", err)
+	}
+
+	recoveries, err := node.StateMinerRecoveries(ctx, maddr, tsk)
+	if err != nil {
+		return nil, fmt.Errorf("get recoveries: %w", err)
+	}
+	recoveryCount, err := recoveries.Count()
+	if err != nil {
+		return nil, fmt.Errorf("count recoveries: %w", err)
+	}
+
+	lost := sapi.Power(big.Mul(big.NewIntUnsigned(faultCount), big.NewIntUnsigned(uint64(size))))
+	return &sapi.FaultInfo{
+		MinerID:     aid,
+		Faults:      faultCount,
+		Recoveries:  recoveryCount,
+		FaultyBytes: &lost,
+	}, nil
+}
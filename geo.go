@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	sapi "static-power/api"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/urfave/cli/v2"
+)
+
+var updateGeoCmd = &cli.Command{
+	Name:  "update-geo",
+	Usage: "resolve the location of miners from their multiaddrs with local geoip databases",
+	Flags: append([]cli.Flag{
+		&cli.StringSliceFlag{
+			Name:     "geoip-db",
+			Usage:    "path to a MaxMind format database, could be repeated to combine city/country and ASN databases",
+			Required: true,
+		},
+	}, recorderFlags...),
+	Action: func(c *cli.Context) error {
+		r, err := openRecorder(c, c.String("network"))
+		if err != nil {
+			return err
+		}
+
+		var dbs []*maxminddb.Reader
+		for _, path := range c.StringSlice("geoip-db") {
+			db, err := maxminddb.Open(path)
+			if err != nil {
+				return fmt.Errorf("open geoip db %s: %w", path, err)
+			}
+			defer db.Close()
+			dbs = append(dbs, db)
+		}
+
+		miners, err := r.GetMiners()
+		if err != nil {
+			return fmt.Errorf("get miners : %w", err)
+		}
+
+		count := 0
+		for i := range miners {
+			miner := &miners[i]
+			geo, err := lookupGeo(dbs, miner)
+			if err != nil {
+				log.Printf("lookup geo for miner %s: %s", miner.ID.String(), err)
+				continue
+			}
+
+			err = r.UpdateGeoInfo(geo)
+			if err != nil {
+				log.Printf("update geo info for(%d) : %s", miner.ID, err)
+				continue
+			}
+			count++
+		}
+		log.Printf("update (%d) geo info of (%d)", count, len(miners))
+		return nil
+	},
+}
+
+// geoRecord picks the fields we need from GeoIP2/GeoLite2 City, Country and ASN databases
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string            `maxminddb:"iso_code"`
+		Names   map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	ASN   uint   `maxminddb:"autonomous_system_number"`
+	ASOrg string `maxminddb:"autonomous_system_organization"`
+}
+
+// lookupGeo locate the first public ip advertised by miner, fields found in later dbs fill the missing ones only
+func lookupGeo(dbs []*maxminddb.Reader, miner *MinerInfo) (*sapi.GeoInfo, error) {
+	ip := minerIP(miner)
+	if ip == nil {
+		return nil, fmt.Errorf("no public ip multiaddr")
+	}
+
+	geo := &sapi.GeoInfo{
+		MinerID: miner.ID,
+		IP:      ip.String(),
+	}
+	for _, db := range dbs {
+		var record geoRecord
+		err := db.Lookup(ip, &record)
+		if err != nil {
+			return nil, fmt.Errorf("lookup %s: %w", ip, err)
+		}
+
+		if geo.Country == "" {
+			geo.Country = record.Country.ISOCode
+		}
+		if geo.Region == "" && len(record.Subdivisions) > 0 {
+			geo.Region = record.Subdivisions[0].Names["en"]
+			if geo.Region == "" {
+				geo.Region = record.Subdivisions[0].ISOCode
+			}
+		}
+		if geo.ASN == 0 {
+			geo.ASN = record.ASN
+			geo.ASOrg = record.ASOrg
+		}
+	}
+	return geo, nil
+}
+
+// minerIP return the first public ip in the multiaddrs of miner, dns addresses are skipped
+func minerIP(miner *MinerInfo) net.IP {
+	if miner.Peer == nil || miner.Peer.Multiaddrs == nil {
+		return nil
+	}
+	ips := sapi.PublicIPs(*miner.Peer.Multiaddrs)
+	if len(ips) == 0 {
+		return nil
+	}
+	return ips[0]
+}
@@ -9,14 +9,10 @@ import (
 	sapi "static-power/api"
 	"static-power/server"
 	"sync"
+	"sync/atomic"
 
-	"github.com/libp2p/go-libp2p"
-	"github.com/libp2p/go-libp2p/core/peer"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/urfave/cli/v2"
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-jsonrpc"
@@ -39,11 +35,25 @@ func main() {
 				Value: "127.0.0.1:8090",
 				Usage: "listen address",
 			},
+			&cli.StringFlag{
+				Name:  "network",
+				Usage: "network of the records to read and write, mainnet if empty, update-peer and watch detect it from the node",
+			},
 		},
 		Commands: []*cli.Command{
 			daemonCmd,
 			updatePowerCmd,
 			updateAgentCmd,
+			updateGeoCmd,
+			watchCmd,
+			updateBlocksCmd,
+			fakeNodeCmd,
+			ingestCmd,
+			statsCmd,
+			proportionCmd,
+			expirationsCmd,
+			minerCmd,
+			minersCmd,
 		},
 	}
 	app.Setup()
@@ -62,17 +72,9 @@ var daemonCmd = &cli.Command{
 		},
 	},
 	Action: func(c *cli.Context) error {
-		var db *gorm.DB
-		var err error
-
-		dsn := c.String("dsn")
 		listen := c.String("listen")
 
-		if dsn == "" {
-			db, err = gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
-		} else {
-			db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
-		}
+		db, err := openDB(c.String("dsn"), "test.db")
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -86,219 +88,245 @@ var daemonCmd = &cli.Command{
 
 var updatePowerCmd = &cli.Command{
 	Name: "update-peer",
-	Flags: []cli.Flag{
-		&cli.StringFlag{
-			Name:  "node",
-			Usage: "entry point for a filecoin node",
-		},
-		&cli.StringFlag{
-			Name:  "token",
-			Usage: "token for a filecoin node",
-		},
+	Flags: append(append(append(nodeFlags, recorderFlags...), outputFlags...),
 		&cli.BoolFlag{
 			Name:  "update-peer",
 			Usage: "update miner peer by the way",
 			Value: true,
 		},
-	},
+		&cli.BoolFlag{
+			Name:  "fast",
+			Usage: "read power of all miners from the power actor state in one pass, fall back to query miners one by one if failed",
+			Value: true,
+		},
+		&cli.BoolFlag{
+			Name:  "deep",
+			Usage: "read active sectors of every miner to measure verified and regular deal power, which is slow",
+		},
+		&cli.BoolFlag{
+			Name:  "expirations",
+			Usage: "read active sectors of every miner to schedule the power expiring in each month, which is slow",
+		},
+		&cli.BoolFlag{
+			Name:  "include-below-min",
+			Usage: "also crawl miners with power below the min power, which are flagged BelowMin",
+		},
+		&cli.BoolFlag{
+			Name:  "full",
+			Usage: "crawl all miners, instead of only those whose actor changed since the last run",
+		},
+		&cli.StringFlag{
+			Name:  "from-car",
+			Usage: "crawl the state in a chain export offline instead of a live node",
+		},
+		&cli.StringFlag{
+			Name:  "state-root",
+			Usage: "state root to crawl with --from-car, the parent state of the head tipset of the export by default",
+		},
+	),
 	Action: func(c *cli.Context) error {
-		listen := c.String("listen")
-		if listen != "" {
-			server.SetHost(listen)
+		ctx := c.Context
+		opts := crawlOptions{
+			fast:            c.Bool("fast"),
+			deep:            c.Bool("deep"),
+			expirations:     c.Bool("expirations"),
+			includeBelowMin: c.Bool("include-below-min"),
 		}
 
-		// get miner power peer and update
-		url := c.String("node")
-		token := c.String("token")
+		// crawl a state snapshot offline, all miners in it are read and no checkpoint is kept
+		if path := c.String("from-car"); path != "" {
+			node, err := openCarNode(ctx, path, c.String("state-root"))
+			if err != nil {
+				return err
+			}
+			defer node.Close()
+			if opts.expirations && node.height == 0 {
+				return fmt.Errorf("expirations are scheduled from the height of the state, which is unknown with --state-root")
+			}
+			opts.height = node.height
 
-		if url == "" {
-			log.Fatal("node url is required")
-		}
-		if token == "" {
-			log.Fatal("node token is required")
+			r, err := openNodeRecorder(c, node)
+			if err != nil {
+				return err
+			}
+			defer closeRecorder(r)
+			opts.onFailure = recordFailure(r)
+
+			miners, err := crawlMiners(ctx, node, types.EmptyTSK, opts)
+			if err != nil {
+				return err
+			}
+			postMinerInfos(r, miners)
+			log.Println("update power info success")
+			return nil
 		}
 
-		node, closer, err := NewRpcClient(url, &token)
+		// get miner power peer and update
+		node, closer, err := connectNode(c)
 		if err != nil {
 			return err
 		}
 		defer closer()
 
-		miners, err := getMinerInfosWithMinPower(node)
+		r, err := openNodeRecorder(c, node)
 		if err != nil {
 			return err
 		}
+		defer closeRecorder(r)
+		opts.onFailure = recordFailure(r)
 
-		for _, miner := range miners {
-			if miner.Power != nil {
-				err := server.UpdatePowerInfo(miner.Power)
-				if err != nil {
-					log.Printf("update power info for(%d) : %s", miner.ID, err)
-				}
-				log.Printf("update power info for(%d) success , RBP(%s), QAP(%s) ", miner.ID, miner.Power.RawBytePower.String(), miner.Power.QualityAdjPower.String())
-			}
-			if miner.Peer != nil {
-				err := server.UpdatePeerInfo(miner.Peer)
-				if err != nil {
-					log.Printf("update peer info for(%d) : %s", miner.ID, err)
-				}
-				log.Printf("update peer info for(%d) success , PeerId(%s), Multiaddrs.len(%d) ", miner.ID, miner.Peer.PeerId, len(*miner.Peer.Multiaddrs))
+		head, err := node.ChainHead(ctx)
+		if err != nil {
+			return fmt.Errorf("get chain head: %w", err)
+		}
+
+		if !c.Bool("full") {
+			opts.only, err = changedSinceCheckpoint(ctx, node, r, updatePeerCheckpoint, head)
+			if err != nil {
+				log.Printf("crawl all miners: %s", err)
 			}
 		}
-		log.Println("update power info success")
-		return nil
+
+		_, err = crawlAndCheckpoint(ctx, node, r, updatePeerCheckpoint, head, opts)
+		return err
 	},
 }
 
-var updateAgentCmd = &cli.Command{
-	Name: "update-agent",
-	Action: func(c *cli.Context) error {
-		listen := c.String("listen")
-		if listen != "" {
-			server.SetHost(listen)
+// recordFailure keep failures of crawl in r
+func recordFailure(r recorder) func(f *failure) {
+	return func(f *failure) {
+		err := r.RecordFailure(f)
+		if err != nil {
+			log.Printf("record failure of miner %d: %s", f.MinerID, err)
 		}
+	}
+}
 
-		miners, err := server.GetMiners()
+// postMinerInfos write the crawled records of miners, records of one miner are written together,
+// return the number of miners failed to write
+func postMinerInfos(r recorder, miners []*MinerInfo) int {
+	failed := 0
+	for _, miner := range miners {
+		miner := miner
+		err := r.Transaction(func(r recorder) error {
+			return postMinerInfo(r, miner)
+		})
 		if err != nil {
-			return fmt.Errorf("get miners : %w", err)
+			log.Printf("update records for(%d) : %s", miner.ID, err)
+			failed++
 		}
+	}
+	return failed
+}
 
-		agents := getAgentInfo(miners)
-
-		log.Printf("update (%d) agent info of (%d), ", len(agents), len(miners))
-		for _, agent := range agents {
-			err := server.UpdateAgentInfo(agent)
-			if err != nil {
-				log.Printf("update agent info for(%d) : %s", agent.MinerID, err)
-			}
-			log.Printf("update agent info for(%d) success , Name(%s)", agent.MinerID, agent.Name)
+func postMinerInfo(r recorder, miner *MinerInfo) error {
+	if miner.Power != nil {
+		err := r.UpdatePowerInfo(miner.Power)
+		if err != nil {
+			return fmt.Errorf("update power info: %w", err)
 		}
-		// get miner get agent
-		return nil
-	},
+		log.Printf("update power info for(%d) success , RBP(%s), QAP(%s) ", miner.ID, miner.Power.RawBytePower.String(), miner.Power.QualityAdjPower.String())
+	}
+	if miner.Peer != nil {
+		err := r.UpdatePeerInfo(miner.Peer)
+		if err != nil {
+			return fmt.Errorf("update peer info: %w", err)
+		}
+		log.Printf("update peer info for(%d) success , PeerId(%s), Multiaddrs.len(%d) ", miner.ID, miner.Peer.PeerId, len(*miner.Peer.Multiaddrs))
+	}
+	if miner.Meta != nil {
+		err := r.UpdateMetaInfo(miner.Meta)
+		if err != nil {
+			return fmt.Errorf("update meta info: %w", err)
+		}
+	}
+	if miner.Sectors != nil {
+		err := r.UpdateSectorPower(miner.Sectors)
+		if err != nil {
+			return fmt.Errorf("update sector power: %w", err)
+		}
+	}
+	if miner.Faults != nil {
+		err := r.UpdateFaultInfo(miner.Faults)
+		if err != nil {
+			return fmt.Errorf("update fault info: %w", err)
+		}
+	}
+	if miner.Balance != nil {
+		err := r.UpdateBalanceInfo(miner.Balance)
+		if err != nil {
+			return fmt.Errorf("update balance info: %w", err)
+		}
+	}
+	if len(miner.Expirations) > 0 {
+		err := r.UpdateExpirations(miner.Expirations)
+		if err != nil {
+			return fmt.Errorf("update expirations: %w", err)
+		}
+	}
+	return nil
 }
 
-func getAgentInfo(miners []sapi.Miner) []*sapi.AgentInfo {
-	ret := make([]*sapi.AgentInfo, 0, len(miners))
-	var wg sync.WaitGroup
-
-	wg.Add(len(miners))
-	throttle := make(chan struct{}, 5000)
-	for i := range miners {
-		miner := &miners[i]
-		throttle <- struct{}{}
-
-		go func(miner *MinerInfo) {
-			defer func() {
-				wg.Done()
-				<-throttle
-				// manager.TrimOpenConns(ctx)
-			}()
-
-			ctx := context.Background()
-
-			info := miner.Peer
-
-			err := func() error {
-				host, err := libp2p.New(libp2p.NoListenAddrs)
-				if err != nil {
-					return err
-				}
-				defer host.Close()
-
-				if info == nil || info.PeerId == "" || len(*info.Multiaddrs) == 0 {
-					return fmt.Errorf("no peer info")
-				}
-				if info.PeerId == "" {
-					return fmt.Errorf("empty peer id")
-				}
-				if info.Multiaddrs == nil || len(*info.Multiaddrs) == 0 {
-					return fmt.Errorf("empty multiaddrs")
-				}
-
-				peerId, err := peer.Decode(info.PeerId)
-				if err != nil {
-					return fmt.Errorf("decode peer id %s: %w", info.PeerId, err)
-				}
-
-				addrInfo := peer.AddrInfo{
-					ID:    peerId,
-					Addrs: []multiaddr.Multiaddr{},
-				}
-
-				for _, addr := range *info.Multiaddrs {
-					maddr, err := multiaddr.NewMultiaddr(addr)
-					if err != nil {
-						return fmt.Errorf("parsing multiaddr %s: %w", addr, err)
-					}
-					addrInfo.Addrs = append(addrInfo.Addrs, maddr)
-				}
-
-				if err := host.Connect(ctx, addrInfo); err != nil {
-					return fmt.Errorf("connecting to peer %s: %w", addrInfo.ID, err)
-				}
-
-				userAgentI, err := host.Peerstore().Get(addrInfo.ID, "AgentVersion")
-				if err != nil {
-					return fmt.Errorf("getting user agent for peer %s: %w", addrInfo.ID, err)
-				}
-
-				userAgent, ok := userAgentI.(string)
-				if !ok {
-					return fmt.Errorf("user agent for peer %s was not a string", addrInfo.ID)
-				}
-
-				agentInfo := &sapi.AgentInfo{
-					MinerID: miner.ID,
-					Name:    userAgent,
-				}
-
-				if agentInfo.Name == "" {
-					return fmt.Errorf("user agent empty")
-				}
+type MinerInfo = sapi.Miner
 
-				if miner.Agent != nil && miner.Agent.Name == agentInfo.Name {
-					return fmt.Errorf("user agent (%s) not change", miner.Agent.Name)
-				}
+type crawlOptions struct {
+	// read power claims from power actor state directly instead of querying miners one by one
+	fast bool
+	// measure deal power from active sectors of every miner
+	deep bool
+	// schedule the power of active sectors of every miner by the month they expire in
+	expirations bool
+	// epoch of the state crawled, expirations are scheduled from it
+	height abi.ChainEpoch
+	// only crawl these miners if not nil, the network power is always read
+	only map[address.Address]struct{}
+	// also crawl miners with some power but below the min power
+	includeBelowMin bool
+	// called with the miners failed to crawl, could be called concurrently
+	onFailure func(f *failure)
+	// counted with every failure if not nil, records of some miners are missing unless it stays 0
+	failures *int64
+}
 
-				ret = append(ret, agentInfo)
-				return nil
-			}()
+func (opts crawlOptions) fail(miner abi.ActorID, stage string, err error) {
+	log.Printf("%s of miner %d: %s", stage, miner, err)
+	if opts.failures != nil {
+		atomic.AddInt64(opts.failures, 1)
+	}
+	if opts.onFailure != nil {
+		opts.onFailure(&failure{MinerID: miner, Stage: stage, Error: err.Error()})
+	}
+}
 
-			if err != nil {
-				log.Printf("get agent for miner %s: %s", miner.ID.String(), err)
-				return
-			}
+func getMinerInfosWithMinPower(node ChainNode, opts crawlOptions) ([]*MinerInfo, error) {
+	ctx := context.Background()
 
-		}(miner)
+	// pin the tipset, so all miners are read from the same state
+	head, err := node.ChainHead(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get chain head: %w", err)
 	}
-	wg.Wait()
-	return ret
+	opts.height = head.Height()
+	return crawlMiners(ctx, node, head.Key(), opts)
 }
 
-type MinerInfo = sapi.Miner
-
-func getMinerInfosWithMinPower(node api.FullNode) ([]*MinerInfo, error) {
+// crawlMiners read network power and miners with min power at tipset tsk
+func crawlMiners(ctx context.Context, node ChainNode, tsk types.TipSetKey, opts crawlOptions) ([]*MinerInfo, error) {
 	ret := make([]*MinerInfo, 0)
-	ctx := context.Background()
-	miners, err := node.StateListMiners(ctx, types.EmptyTSK)
+
+	claims, total, err := getPowerClaims(ctx, node, tsk, opts)
 	if err != nil {
 		return nil, err
 	}
-	log.Println("Total SPs on chain: ", len(miners))
-
-	var wg sync.WaitGroup
-	wg.Add(len(miners))
-	var lk sync.Mutex
+	log.Println("Total SPs on chain: ", len(claims))
+	if opts.only != nil {
+		log.Println("SPs changed: ", len(opts.only))
+	}
 
 	// get network power
-	if len(miners) != 0 {
-		power, err := node.StateMinerPower(ctx, miners[0], types.EmptyTSK)
-		if err != nil {
-			panic(err)
-		}
-		rbp := sapi.Power(power.TotalPower.RawBytePower)
-		qap := sapi.Power(power.TotalPower.QualityAdjPower)
+	if total != nil {
+		rbp := sapi.Power(total.RawBytePower)
+		qap := sapi.Power(total.QualityAdjPower)
 		powerInfo := sapi.PowerInfo{
 			MinerID:         sapi.NetWork,
 			RawBytePower:    &rbp,
@@ -311,47 +339,100 @@ func getMinerInfosWithMinPower(node api.FullNode) ([]*MinerInfo, error) {
 		ret = append(ret, mi)
 	}
 
+	var wg sync.WaitGroup
+	var lk sync.Mutex
+
 	throttle := make(chan struct{}, 100)
-	for i := range miners {
-		miner := miners[i]
+	for miner, claim := range claims {
+		if _, ok := opts.only[miner]; opts.only != nil && !ok {
+			continue
+		}
+		if !claim.HasMinPower && (!opts.includeBelowMin || claim.RawBytePower.IsZero()) {
+			// the power of miners with some is still recorded, or the one recorded while they had min power stays the latest
+			if !claim.RawBytePower.IsZero() {
+				id, err := address.IDFromAddress(miner)
+				if err != nil {
+					log.Println("miner id error: ", err)
+					continue
+				}
+				rbp := sapi.Power(claim.RawBytePower)
+				qap := sapi.Power(claim.QualityAdjPower)
+				lk.Lock()
+				ret = append(ret, &MinerInfo{
+					ID: abi.ActorID(id),
+					Power: &sapi.PowerInfo{
+						MinerID:         abi.ActorID(id),
+						RawBytePower:    &rbp,
+						QualityAdjPower: &qap,
+						BelowMin:        true,
+					},
+				})
+				lk.Unlock()
+			}
+			continue
+		}
+
+		wg.Add(1)
 		throttle <- struct{}{}
-		go func(miner address.Address) {
+		go func(miner address.Address, claim powerClaim) {
 			defer wg.Done()
 			defer func() {
 				<-throttle
 			}()
 
-			power, err := node.StateMinerPower(ctx, miner, types.EmptyTSK)
-			if err != nil {
-				panic(err)
-			}
-
-			if !power.HasMinPower {
-				return
-			}
-
-			info, err := node.StateMinerInfo(ctx, miner, types.EmptyTSK)
-			if err != nil {
-				panic(err)
-			}
-
 			id, err := address.IDFromAddress(miner)
 			if err != nil {
 				log.Println("miner id error: ", err)
 			}
 			aid := abi.ActorID(id)
 
-			rbp := sapi.Power(power.MinerPower.RawBytePower)
-			qap := sapi.Power(power.MinerPower.QualityAdjPower)
+			info, err := node.StateMinerInfo(ctx, miner, tsk)
+			if err != nil {
+				opts.fail(aid, "get info", err)
+				return
+			}
+
+			rbp := sapi.Power(claim.RawBytePower)
+			qap := sapi.Power(claim.QualityAdjPower)
 			powerInfo := sapi.PowerInfo{
 				MinerID:         aid,
 				RawBytePower:    &rbp,
 				QualityAdjPower: &qap,
+				BelowMin:        !claim.HasMinPower,
 			}
 
 			mi := &MinerInfo{
 				ID:    aid,
 				Power: &powerInfo,
+				Meta:  minerMeta(ctx, node, aid, info),
+			}
+
+			faults, err := measureFaults(ctx, node, tsk, miner, aid, info.SectorSize)
+			if err != nil {
+				opts.fail(aid, "measure faults", err)
+			} else {
+				mi.Faults = faults
+			}
+
+			balance, err := measureBalance(ctx, node, tsk, miner, aid)
+			if err != nil {
+				opts.fail(aid, "measure balance", err)
+			} else {
+				mi.Balance = balance
+			}
+
+			if opts.deep || opts.expirations {
+				sectors, err := node.StateMinerActiveSectors(ctx, miner, tsk)
+				if err != nil {
+					opts.fail(aid, "get active sectors", err)
+				} else {
+					if opts.deep {
+						mi.Sectors = sumSectors(aid, info.SectorSize, sectors)
+					}
+					if opts.expirations {
+						mi.Expirations = scheduleExpirations(aid, info.SectorSize, opts.height, sectors)
+					}
+				}
 			}
 
 			if info.PeerId != nil || len(info.Multiaddrs) > 0 {
@@ -360,6 +441,7 @@ func getMinerInfosWithMinPower(node api.FullNode) ([]*MinerInfo, error) {
 					maddr, err := multiaddr.NewMultiaddrBytes(addr)
 					if err != nil {
 						log.Println("parse multiaddr error: ", err)
+						continue
 					}
 					multiAddress = append(multiAddress, maddr.String())
 				}
@@ -374,13 +456,46 @@ func getMinerInfosWithMinPower(node api.FullNode) ([]*MinerInfo, error) {
 			lk.Lock()
 			ret = append(ret, mi)
 			lk.Unlock()
-		}(miner)
+		}(miner, claim)
 	}
 
 	wg.Wait()
 	return ret, nil
 }
 
+func minerMeta(ctx context.Context, node ChainNode, miner abi.ActorID, info api.MinerInfo) *sapi.MinerMeta {
+	// addresses in miner info should be ID addresses already, resolve them in case not,
+	// so miners of the same operator could be grouped by them
+	resolve := func(addr address.Address) string {
+		if addr == address.Undef {
+			return ""
+		}
+		if addr.Protocol() == address.ID {
+			return addr.String()
+		}
+		id, err := node.StateLookupID(ctx, addr, types.EmptyTSK)
+		if err != nil {
+			log.Printf("lookup id of %s for miner %d: %s", addr, miner, err)
+			return addr.String()
+		}
+		return id.String()
+	}
+
+	controls := sapi.Addresses{}
+	for _, addr := range info.ControlAddresses {
+		controls = append(controls, resolve(addr))
+	}
+	return &sapi.MinerMeta{
+		MinerID:             miner,
+		Owner:               resolve(info.Owner),
+		Worker:              resolve(info.Worker),
+		ControlAddresses:    &controls,
+		Beneficiary:         resolve(info.Beneficiary),
+		SectorSize:          info.SectorSize,
+		WindowPoStProofType: info.WindowPoStProofType,
+	}
+}
+
 func NewRpcClient(endpoint string, token *string) (api.FullNode, jsonrpc.ClientCloser, error) {
 	requestHeader := http.Header{}
 	if token != nil {
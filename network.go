@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	sapi "static-power/api"
+
+	"github.com/urfave/cli/v2"
+)
+
+// mainnetName is the network name mainnet nodes report, a leftover of the testnet the mainnet was launched from
+const mainnetName = "testnetnet"
+
+// nodeNetwork get the network of node by the name records are stamped with, mainnet is the default network
+func nodeNetwork(ctx context.Context, node ChainNode) (string, error) {
+	name, err := node.StateNetworkName(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get network name: %w", err)
+	}
+	if name == mainnetName {
+		return sapi.DefaultNetwork, nil
+	}
+	return string(name), nil
+}
+
+// openNodeRecorder detect the network of node and open the recorder of it,
+// it's an error if the network given by flag is a different one
+func openNodeRecorder(c *cli.Context, node ChainNode) (recorder, error) {
+	network, err := nodeNetwork(c.Context, node)
+	if err != nil {
+		return nil, err
+	}
+	if flag := c.String("network"); flag != "" && flag != network {
+		return nil, fmt.Errorf("node is on network %s rather than %s", network, flag)
+	}
+	log.Printf("node is on network %s", network)
+	return openRecorder(c, network)
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	sapi "static-power/api"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/urfave/cli/v2"
+)
+
+// kinds of lines in an output file
+const (
+	kindPower       = "power"
+	kindPeer        = "peer"
+	kindMeta        = "meta"
+	kindSectors     = "sectors"
+	kindFaults      = "faults"
+	kindBalance     = "balance"
+	kindExpirations = "expirations"
+	kindBlocks      = "blocks"
+	kindAgent       = "agent"
+	kindIdentify    = "identify"
+	kindProbe       = "probe"
+	kindAddrs       = "addrs"
+	kindGeo         = "geo"
+	kindCheckpoint  = "checkpoint"
+	kindFailure     = "failure"
+)
+
+// failure is a miner the collector failed to get records of
+type failure struct {
+	MinerID abi.ActorID
+	Network string
+	Stage   string
+	Error   string
+}
+
+// outputLine is one record in an output file, Record is the json of the record
+type outputLine struct {
+	Kind    string
+	MinerID abi.ActorID
+	Record  json.RawMessage
+}
+
+var outputFlags = []cli.Flag{
+	&cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "write records to stdout, or the file of --output, instead of the daemon or database",
+	},
+	&cli.StringFlag{
+		Name:  "output",
+		Usage: "write records and failures to a .jsonl or .csv file instead of the daemon or database, see ingest",
+	},
+}
+
+// fileRecorder write records to a file, miners and checkpoints are still read from base
+type fileRecorder struct {
+	base    recorder
+	network string
+
+	lk    sync.Mutex
+	write func(line *outputLine) error
+	file  *os.File
+}
+
+// newFileRecorder write records to path, stdout if path is -, in csv if path ends with .csv, otherwise in json lines
+func newFileRecorder(base recorder, network string, path string) (*fileRecorder, error) {
+	r := &fileRecorder{base: base, network: network, file: os.Stdout}
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("create output: %w", err)
+		}
+		r.file = f
+	}
+
+	if strings.HasSuffix(path, ".csv") {
+		cw := csv.NewWriter(r.file)
+		r.write = func(line *outputLine) error {
+			err := cw.Write([]string{line.Kind, strconv.FormatUint(uint64(line.MinerID), 10), string(line.Record)})
+			if err != nil {
+				return err
+			}
+			cw.Flush()
+			return cw.Error()
+		}
+		err := cw.Write([]string{"kind", "miner_id", "record"})
+		if err == nil {
+			cw.Flush()
+			err = cw.Error()
+		}
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("write csv header: %w", err)
+		}
+	} else {
+		enc := json.NewEncoder(r.file)
+		r.write = func(line *outputLine) error {
+			return enc.Encode(line)
+		}
+	}
+	return r, nil
+}
+
+func (r *fileRecorder) lockedWrite(fn func() error) error {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	return fn()
+}
+
+// Close close the output file, stdout is left open
+func (r *fileRecorder) Close() error {
+	if r.file == os.Stdout {
+		return nil
+	}
+	return r.file.Close()
+}
+
+func (r *fileRecorder) record(kind string, miner abi.ActorID, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal %s record: %w", kind, err)
+	}
+	return r.lockedWrite(func() error {
+		return r.write(&outputLine{Kind: kind, MinerID: miner, Record: data})
+	})
+}
+
+func (r *fileRecorder) GetMiners() ([]sapi.Miner, error) {
+	return r.base.GetMiners()
+}
+
+func (r *fileRecorder) GetCheckpoint(name string) (*sapi.Checkpoint, error) {
+	return r.base.GetCheckpoint(name)
+}
+
+func (r *fileRecorder) UpdatePowerInfo(power *sapi.PowerInfo) error {
+	power.Network = r.network
+	return r.record(kindPower, power.MinerID, power)
+}
+
+func (r *fileRecorder) UpdatePeerInfo(peer *sapi.PeerInfo) error {
+	peer.Network = r.network
+	return r.record(kindPeer, peer.MinerID, peer)
+}
+
+func (r *fileRecorder) UpdateMetaInfo(meta *sapi.MinerMeta) error {
+	meta.Network = r.network
+	return r.record(kindMeta, meta.MinerID, meta)
+}
+
+func (r *fileRecorder) UpdateSectorPower(sectors *sapi.SectorPower) error {
+	sectors.Network = r.network
+	return r.record(kindSectors, sectors.MinerID, sectors)
+}
+
+func (r *fileRecorder) UpdateFaultInfo(faults *sapi.FaultInfo) error {
+	faults.Network = r.network
+	return r.record(kindFaults, faults.MinerID, faults)
+}
+
+func (r *fileRecorder) UpdateBalanceInfo(balance *sapi.BalanceInfo) error {
+	balance.Network = r.network
+	return r.record(kindBalance, balance.MinerID, balance)
+}
+
+func (r *fileRecorder) UpdateExpirations(exps []sapi.SectorExpiration) error {
+	if len(exps) == 0 {
+		return nil
+	}
+	for i := range exps {
+		exps[i].Network = r.network
+	}
+	return r.record(kindExpirations, exps[0].MinerID, exps)
+}
+
+// UpdateBlocks write the blocks of a tipset in one line, miner id is that of the first block
+func (r *fileRecorder) UpdateBlocks(blocks []sapi.BlockInfo) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	for i := range blocks {
+		blocks[i].Network = r.network
+	}
+	return r.record(kindBlocks, blocks[0].MinerID, blocks)
+}
+
+func (r *fileRecorder) UpdateAgentInfo(agent *sapi.AgentInfo) error {
+	agent.Network = r.network
+	return r.record(kindAgent, agent.MinerID, agent)
+}
+
+func (r *fileRecorder) UpdateIdentifyInfo(identify *sapi.IdentifyInfo) error {
+	identify.Network = r.network
+	return r.record(kindIdentify, identify.MinerID, identify)
+}
+
+func (r *fileRecorder) UpdateProbeResult(probe *sapi.ProbeResult) error {
+	probe.Network = r.network
+	return r.record(kindProbe, probe.MinerID, probe)
+}
+
+func (r *fileRecorder) UpdateAddrProbes(probes []sapi.AddrProbe) error {
+	if len(probes) == 0 {
+		return nil
+	}
+	for i := range probes {
+		probes[i].Network = r.network
+	}
+	return r.record(kindAddrs, probes[0].MinerID, probes)
+}
+
+func (r *fileRecorder) UpdateGeoInfo(geo *sapi.GeoInfo) error {
+	geo.Network = r.network
+	return r.record(kindGeo, geo.MinerID, geo)
+}
+
+func (r *fileRecorder) UpdateCheckpoint(cp *sapi.Checkpoint) error {
+	cp.Network = r.network
+	return r.record(kindCheckpoint, 0, cp)
+}
+
+func (r *fileRecorder) RecordFailure(f *failure) error {
+	f.Network = r.network
+	return r.record(kindFailure, f.MinerID, f)
+}
+
+func (r *fileRecorder) Transaction(fn func(r recorder) error) error {
+	return fn(r)
+}
+
+var ingestCmd = &cli.Command{
+	Name:      "ingest",
+	Usage:     "load the records written by --output into the daemon or database",
+	ArgsUsage: "<results.jsonl|results.csv>",
+	Flags:     recorderFlags,
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 1 {
+			return errors.New("one output file is required")
+		}
+		r, err := openRecorder(c, c.String("network"))
+		if err != nil {
+			return err
+		}
+
+		count, failures := 0, 0
+		err = readOutput(c.Args().First(), func(line *outputLine) error {
+			if line.Kind == kindFailure {
+				failures++
+				return nil
+			}
+			err := ingestLine(r, line)
+			if err != nil {
+				return fmt.Errorf("ingest %s record of miner %d: %w", line.Kind, line.MinerID, err)
+			}
+			count++
+			return nil
+		})
+		log.Printf("ingest (%d) records, skip (%d) failures", count, failures)
+		return err
+	},
+}
+
+// readOutput call fn with every line of an output file
+func readOutput(path string, fn func(line *outputLine) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(path, ".csv") {
+		cr := csv.NewReader(f)
+		header := true
+		for {
+			row, err := cr.Read()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if header {
+				header = false
+				continue
+			}
+			if len(row) != 3 {
+				return fmt.Errorf("expect 3 columns rather than %d", len(row))
+			}
+			id, err := strconv.ParseUint(row[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("parse miner id %s: %w", row[1], err)
+			}
+			err = fn(&outputLine{Kind: row[0], MinerID: abi.ActorID(id), Record: json.RawMessage(row[2])})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	dec := json.NewDecoder(f)
+	for {
+		var line outputLine
+		err := dec.Decode(&line)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = fn(&line)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ingestLine write the record of line to r
+func ingestLine(r recorder, line *outputLine) error {
+	decode := func(v interface{}) error {
+		return json.Unmarshal(line.Record, v)
+	}
+
+	switch line.Kind {
+	case kindPower:
+		var power sapi.PowerInfo
+		if err := decode(&power); err != nil {
+			return err
+		}
+		return r.UpdatePowerInfo(&power)
+	case kindPeer:
+		var peer sapi.PeerInfo
+		if err := decode(&peer); err != nil {
+			return err
+		}
+		return r.UpdatePeerInfo(&peer)
+	case kindMeta:
+		var meta sapi.MinerMeta
+		if err := decode(&meta); err != nil {
+			return err
+		}
+		return r.UpdateMetaInfo(&meta)
+	case kindSectors:
+		var sectors sapi.SectorPower
+		if err := decode(&sectors); err != nil {
+			return err
+		}
+		return r.UpdateSectorPower(&sectors)
+	case kindFaults:
+		var faults sapi.FaultInfo
+		if err := decode(&faults); err != nil {
+			return err
+		}
+		return r.UpdateFaultInfo(&faults)
+	case kindBalance:
+		var balance sapi.BalanceInfo
+		if err := decode(&balance); err != nil {
+			return err
+		}
+		return r.UpdateBalanceInfo(&balance)
+	case kindExpirations:
+		var exps []sapi.SectorExpiration
+		if err := decode(&exps); err != nil {
+			return err
+		}
+		return r.UpdateExpirations(exps)
+	case kindBlocks:
+		var blocks []sapi.BlockInfo
+		if err := decode(&blocks); err != nil {
+			return err
+		}
+		return r.UpdateBlocks(blocks)
+	case kindAgent:
+		var agent sapi.AgentInfo
+		if err := decode(&agent); err != nil {
+			return err
+		}
+		return r.UpdateAgentInfo(&agent)
+	case kindIdentify:
+		var identify sapi.IdentifyInfo
+		if err := decode(&identify); err != nil {
+			return err
+		}
+		return r.UpdateIdentifyInfo(&identify)
+	case kindProbe:
+		var probe sapi.ProbeResult
+		if err := decode(&probe); err != nil {
+			return err
+		}
+		return r.UpdateProbeResult(&probe)
+	case kindAddrs:
+		var probes []sapi.AddrProbe
+		if err := decode(&probes); err != nil {
+			return err
+		}
+		return r.UpdateAddrProbes(probes)
+	case kindGeo:
+		var geo sapi.GeoInfo
+		if err := decode(&geo); err != nil {
+			return err
+		}
+		return r.UpdateGeoInfo(&geo)
+	case kindCheckpoint:
+		var cp sapi.Checkpoint
+		if err := decode(&cp); err != nil {
+			return err
+		}
+		return r.UpdateCheckpoint(&cp)
+	default:
+		return fmt.Errorf("unknown kind %s", line.Kind)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/blockstore"
+	"github.com/filecoin-project/lotus/chain/actors/adt"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/power"
+	"github.com/filecoin-project/lotus/chain/types"
+	cbor "github.com/ipfs/go-ipld-cbor"
+)
+
+// powerClaim is the power of one miner, or the total power of network
+type powerClaim struct {
+	RawBytePower    abi.StoragePower
+	QualityAdjPower abi.StoragePower
+	HasMinPower     bool
+}
+
+// getPowerClaims get the claims of all miners and the total power of network at tsk,
+// read the claims table of power actor in one pass if fast, and fall back to query miners one by one
+func getPowerClaims(ctx context.Context, node ChainNode, tsk types.TipSetKey, opts crawlOptions) (map[address.Address]powerClaim, *powerClaim, error) {
+	if opts.fast {
+		claims, total, err := loadPowerClaims(ctx, node, tsk)
+		if err == nil {
+			return claims, total, nil
+		}
+		log.Printf("load claims from power actor failed, fall back to query miners one by one: %s", err)
+	}
+	return queryPowerClaims(ctx, node, tsk, opts)
+}
+
+// loadPowerClaims walk the claims HAMT of power actor locally, blocks are read through ChainReadObj once
+func loadPowerClaims(ctx context.Context, node ChainNode, tsk types.TipSetKey) (map[address.Address]powerClaim, *powerClaim, error) {
+	act, err := node.StateGetActor(ctx, power.Address, tsk)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get power actor: %w", err)
+	}
+
+	// checking min power looks the miner up in the claims again, keep the nodes read so each is fetched once
+	store := adt.WrapStore(ctx, cbor.NewCborStore(blockstore.NewAPIBlockstore(newCachedReader(node))))
+	st, err := power.Load(store, act)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load power actor state: %w", err)
+	}
+
+	total, err := st.TotalPower()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get total power: %w", err)
+	}
+
+	claims := make(map[address.Address]powerClaim)
+	err = st.ForEachClaim(func(miner address.Address, claim power.Claim) error {
+		hasMinPower, err := st.MinerNominalPowerMeetsConsensusMinimum(miner)
+		if err != nil {
+			return fmt.Errorf("check min power of %s: %w", miner, err)
+		}
+		claims[miner] = powerClaim{
+			RawBytePower:    claim.RawBytePower,
+			QualityAdjPower: claim.QualityAdjPower,
+			HasMinPower:     hasMinPower,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("iterate claims: %w", err)
+	}
+
+	return claims, &powerClaim{
+		RawBytePower:    total.RawBytePower,
+		QualityAdjPower: total.QualityAdjPower,
+	}, nil
+}
+
+// queryPowerClaims call StateMinerPower for every miner, miners failed are left out and reported to opts
+func queryPowerClaims(ctx context.Context, node ChainNode, tsk types.TipSetKey, opts crawlOptions) (map[address.Address]powerClaim, *powerClaim, error) {
+	miners, err := node.StateListMiners(ctx, tsk)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims := make(map[address.Address]powerClaim, len(miners))
+	var total *powerClaim
+	var lk sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(len(miners))
+	throttle := make(chan struct{}, 100)
+	for i := range miners {
+		miner := miners[i]
+		throttle <- struct{}{}
+		go func(miner address.Address) {
+			defer wg.Done()
+			defer func() {
+				<-throttle
+			}()
+
+			power, err := node.StateMinerPower(ctx, miner, tsk)
+			if err != nil {
+				id, _ := address.IDFromAddress(miner)
+				opts.fail(abi.ActorID(id), "get power", err)
+				return
+			}
+
+			lk.Lock()
+			defer lk.Unlock()
+			if total == nil {
+				total = &powerClaim{
+					RawBytePower:    power.TotalPower.RawBytePower,
+					QualityAdjPower: power.TotalPower.QualityAdjPower,
+				}
+			}
+			claims[miner] = powerClaim{
+				RawBytePower:    power.MinerPower.RawBytePower,
+				QualityAdjPower: power.MinerPower.QualityAdjPower,
+				HasMinPower:     power.HasMinPower,
+			}
+		}(miner)
+	}
+	wg.Wait()
+
+	if total == nil && len(miners) != 0 {
+		return nil, nil, fmt.Errorf("failed to get power of all %d miners", len(miners))
+	}
+	return claims, total, nil
+}
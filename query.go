@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	sapi "static-power/api"
+	"static-power/server"
+
+	"github.com/urfave/cli/v2"
+)
+
+// formats the query commands print in
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+var formatFlag = &cli.StringFlag{
+	Name:    "format",
+	Aliases: []string{"f"},
+	Usage:   "print as table, json or csv",
+	Value:   formatTable,
+}
+
+var belowMinFlag = &cli.StringFlag{
+	Name:  "below-min",
+	Usage: "count miners below min power, include, exclude or only",
+	Value: sapi.BelowMinExclude,
+}
+
+var statsCmd = &cli.Command{
+	Name:  "stats",
+	Usage: "print the power of venus and lotus miners",
+	Flags: []cli.Flag{formatFlag, belowMinFlag},
+	Action: func(c *cli.Context) error {
+		if err := connectDaemon(c); err != nil {
+			return err
+		}
+
+		stats := map[string]*sapi.StaticInfo{}
+		rows := [][]string{}
+		for _, impl := range []string{sapi.ImplVenus, sapi.ImplLotus} {
+			s, err := server.GetStatic(impl)
+			if err != nil {
+				return err
+			}
+			stats[impl] = s
+			rows = append(rows, []string{
+				impl,
+				strconv.Itoa(s.Count),
+				formatPiB(s.RBP),
+				formatPiB(s.QAP),
+				formatPiB(s.DCP),
+				formatPiB(s.CCP),
+				formatPiB(s.DealP),
+				strconv.Itoa(s.Measured),
+			})
+		}
+		header := []string{"impl", "count", "rbp_pib", "qap_pib", "dcp_pib", "ccp_pib", "deal_pib", "measured"}
+		return printRecords(c, stats, header, rows)
+	},
+}
+
+var proportionCmd = &cli.Command{
+	Name:  "proportion",
+	Usage: "print the proportion of venus in the QAP of venus and lotus miners",
+	Flags: []cli.Flag{formatFlag, belowMinFlag},
+	Action: func(c *cli.Context) error {
+		if err := connectDaemon(c); err != nil {
+			return err
+		}
+
+		p, err := server.GetProportion()
+		if err != nil {
+			return err
+		}
+		rows := [][]string{{strconv.FormatFloat(p, 'f', 6, 64)}}
+		return printRecords(c, map[string]float64{"proportion": p}, []string{"proportion"}, rows)
+	},
+}
+
+var expirationsCmd = &cli.Command{
+	Name:  "expirations",
+	Usage: "print the power of implementations expiring in each month and their share left after it",
+	Flags: []cli.Flag{
+		formatFlag,
+		belowMinFlag,
+		&cli.IntFlag{
+			Name:  "months",
+			Usage: "months of 30 days to forecast",
+			Value: 12,
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if err := connectDaemon(c); err != nil {
+			return err
+		}
+
+		s, err := server.GetExpirationStatic(c.Int("months"))
+		if err != nil {
+			return err
+		}
+		header := []string{"month", "impl", "miners", "sectors", "rbp_pib", "qap_pib", "cc_rbp_pib", "remaining_rbp_pib", "remaining_qap_pib", "qap_share"}
+		rows := [][]string{}
+		for _, e := range s {
+			rows = append(rows, []string{
+				strconv.Itoa(e.Month),
+				e.Implementation,
+				strconv.Itoa(e.Miners),
+				strconv.FormatUint(e.Sectors, 10),
+				formatPiB(e.RBP),
+				formatPiB(e.QAP),
+				formatPiB(e.CCRBP),
+				formatPiB(e.RemainingRBP),
+				formatPiB(e.RemainingQAP),
+				strconv.FormatFloat(e.QAPShare, 'f', 6, 64),
+			})
+		}
+		return printRecords(c, s, header, rows)
+	},
+}
+
+var minerCmd = &cli.Command{
+	Name:  "miner",
+	Usage: "query one miner",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "show",
+			Usage:     "print the latest records of a miner",
+			ArgsUsage: "<f01234>",
+			Flags:     []cli.Flag{formatFlag},
+			Action: func(c *cli.Context) error {
+				if c.NArg() != 1 {
+					return fmt.Errorf("one miner address is required")
+				}
+				addr := c.Args().First()
+				if _, err := sapi.ParseMinerID(addr); err != nil {
+					return err
+				}
+				if err := connectDaemon(c); err != nil {
+					return err
+				}
+
+				detail, err := server.GetMinerDetail(addr)
+				if err != nil {
+					return fmt.Errorf("get miner %s: %w", addr, err)
+				}
+				return printRecords(c, detail, []string{"field", "value"}, minerFields(detail))
+			},
+		},
+	},
+}
+
+var minersCmd = &cli.Command{
+	Name:  "miners",
+	Usage: "query miners",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "list",
+			Usage: "print the latest records of miners",
+			Flags: []cli.Flag{
+				formatFlag,
+				belowMinFlag,
+				&cli.StringFlag{
+					Name:  "impl",
+					Usage: "only list miners of venus, lotus, others or unknown",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if err := connectDaemon(c); err != nil {
+					return err
+				}
+
+				miners, err := server.GetMinersByImpl(c.String("impl"))
+				if err != nil {
+					return err
+				}
+				sort.Slice(miners, func(i, j int) bool {
+					return miners[i].ID < miners[j].ID
+				})
+
+				header := []string{"miner_id", "agent", "rbp", "qap", "below_min", "peer_id", "country"}
+				rows := [][]string{}
+				for _, miner := range miners {
+					rows = append(rows, minerRow(miner))
+				}
+				return printRecords(c, miners, header, rows)
+			},
+		},
+	},
+}
+
+// connectDaemon point the client to the daemon and network given by flags
+func connectDaemon(c *cli.Context) error {
+	if listen := c.String("listen"); listen != "" {
+		server.SetHost(listen)
+	}
+	server.SetNetwork(c.String("network"))
+
+	sel, err := sapi.ParseBelowMin(c.String("below-min"))
+	if err != nil {
+		return err
+	}
+	server.SetBelowMin(sel)
+	return nil
+}
+
+func minerRow(miner sapi.Miner) []string {
+	row := []string{fmt.Sprintf("f0%d", miner.ID), "", "", "", "", "", ""}
+	if miner.Agent != nil {
+		row[1] = miner.Agent.Name
+	}
+	if miner.Power != nil {
+		row[2] = miner.Power.RawBytePower.String()
+		row[3] = miner.Power.QualityAdjPower.String()
+		row[4] = strconv.FormatBool(miner.Power.BelowMin)
+	}
+	if miner.Peer != nil {
+		row[5] = miner.Peer.PeerId
+	}
+	if miner.Geo != nil {
+		row[6] = miner.Geo.Country
+	}
+	return row
+}
+
+// minerFields flatten the detail of a miner into field and value pairs
+func minerFields(detail *sapi.MinerDetail) [][]string {
+	rows := [][]string{
+		{"miner_id", fmt.Sprintf("f0%d", detail.ID)},
+		{"implementation", detail.Implementation},
+		{"has_deal", strconv.FormatBool(detail.HasDeal)},
+		{"rbp_pib", formatPiB(detail.RBP)},
+		{"qap_pib", formatPiB(detail.QAP)},
+		{"dcp_pib", formatPiB(detail.DCP)},
+		{"ccp_pib", formatPiB(detail.CCP)},
+	}
+	add := func(field, value string) {
+		if value != "" {
+			rows = append(rows, []string{field, value})
+		}
+	}
+	if detail.Power != nil {
+		add("below_min", strconv.FormatBool(detail.Power.BelowMin))
+	}
+	if detail.Agent != nil {
+		add("agent", detail.Agent.Name)
+	}
+	if detail.Peer != nil {
+		add("peer_id", detail.Peer.PeerId)
+		if detail.Peer.Multiaddrs != nil {
+			add("multiaddrs", strings.Join(*detail.Peer.Multiaddrs, " "))
+		}
+	}
+	if detail.Probe != nil {
+		add("probe", detail.Probe.Outcome)
+	}
+	if detail.Meta != nil {
+		add("owner", detail.Meta.Owner)
+		add("worker", detail.Meta.Worker)
+		add("beneficiary", detail.Meta.Beneficiary)
+		add("sector_size", detail.Meta.SectorSize.ShortString())
+	}
+	if detail.Faults != nil {
+		add("faults", strconv.FormatUint(detail.Faults.Faults, 10))
+		add("recoveries", strconv.FormatUint(detail.Faults.Recoveries, 10))
+	}
+	if detail.Balance != nil {
+		add("balance", detail.Balance.Balance.String())
+		add("available", detail.Balance.Available.String())
+		add("initial_pledge", detail.Balance.InitialPledge.String())
+	}
+	if detail.Geo != nil {
+		add("ip", detail.Geo.IP)
+		add("country", detail.Geo.Country)
+		add("asn", detail.Geo.ASOrg)
+	}
+
+	kinds := make([]string, 0, len(detail.Ages))
+	for kind := range detail.Ages {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		add("age_"+kind, strconv.FormatInt(detail.Ages[kind], 10)+"s")
+	}
+	return rows
+}
+
+func formatPiB(v float64) string {
+	return strconv.FormatFloat(v, 'f', 4, 64)
+}
+
+// printRecords print v as json, or rows under header as csv or an aligned table, by the format flag
+func printRecords(c *cli.Context, v interface{}, header []string, rows [][]string) error {
+	return writeRecords(c.App.Writer, c.String("format"), v, header, rows)
+}
+
+func writeRecords(w io.Writer, format string, v interface{}, header []string, rows [][]string) error {
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case formatTable, "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown format %s, expect table, json or csv", format)
+	}
+}
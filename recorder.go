@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	sapi "static-power/api"
+	"static-power/server"
+
+	"github.com/urfave/cli/v2"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// recorder is where collectors read miners from and write records to
+type recorder interface {
+	GetMiners() ([]sapi.Miner, error)
+	GetCheckpoint(name string) (*sapi.Checkpoint, error)
+
+	UpdatePowerInfo(power *sapi.PowerInfo) error
+	UpdatePeerInfo(peer *sapi.PeerInfo) error
+	UpdateMetaInfo(meta *sapi.MinerMeta) error
+	UpdateSectorPower(sectors *sapi.SectorPower) error
+	UpdateFaultInfo(faults *sapi.FaultInfo) error
+	UpdateBalanceInfo(balance *sapi.BalanceInfo) error
+	UpdateExpirations(exps []sapi.SectorExpiration) error
+	UpdateBlocks(blocks []sapi.BlockInfo) error
+	UpdateAgentInfo(agent *sapi.AgentInfo) error
+	UpdateIdentifyInfo(identify *sapi.IdentifyInfo) error
+	UpdateProbeResult(probe *sapi.ProbeResult) error
+	UpdateAddrProbes(probes []sapi.AddrProbe) error
+	UpdateGeoInfo(geo *sapi.GeoInfo) error
+	UpdateCheckpoint(cp *sapi.Checkpoint) error
+	// RecordFailure keep a miner failed to crawl, recorders other than the file one only log it
+	RecordFailure(f *failure) error
+
+	// Transaction run fn with a recorder whose writes are committed together if the recorder supports
+	Transaction(fn func(r recorder) error) error
+}
+
+var recorderFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "dsn",
+		Usage: "write to the mysql database directly instead of posting to the daemon",
+	},
+	&cli.StringFlag{
+		Name:  "db-path",
+		Usage: "write to the sqlite database at path directly instead of posting to the daemon",
+	},
+}
+
+// openRecorder open the database given by flags, or post to the daemon at listen if none,
+// records are written to the file of --output instead if given, or stdout on --dry-run
+func openRecorder(c *cli.Context, network string) (recorder, error) {
+	r, err := openBaseRecorder(c, network)
+	if err != nil {
+		return nil, err
+	}
+
+	output := c.String("output")
+	if output == "" && c.Bool("dry-run") {
+		output = "-"
+	}
+	if output == "" {
+		return r, nil
+	}
+	log.Printf("write records of %s to %s", network, output)
+	return newFileRecorder(r, network, output)
+}
+
+// closeRecorder close r if it holds a file
+func closeRecorder(r recorder) {
+	if closer, ok := r.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Printf("close recorder: %s", err)
+		}
+	}
+}
+
+func openBaseRecorder(c *cli.Context, network string) (recorder, error) {
+	dsn, path := c.String("dsn"), c.String("db-path")
+	if dsn != "" || path != "" {
+		db, err := openDB(dsn, path)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("write records of %s to database directly", network)
+		return &dbRecorder{api: sapi.NewApi(db).ForNetwork(network)}, nil
+	}
+
+	listen := c.String("listen")
+	if listen != "" {
+		server.SetHost(listen)
+	}
+	server.SetNetwork(network)
+	return httpRecorder{}, nil
+}
+
+// openDB open the mysql database by dsn, or the sqlite database at path if dsn is empty
+func openDB(dsn string, path string) (*gorm.DB, error) {
+	var db *gorm.DB
+	var err error
+	if dsn == "" {
+		db, err = gorm.Open(sqlite.Open(path), &gorm.Config{})
+	} else {
+		db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return db, nil
+}
+
+// httpRecorder post records to the daemon one by one
+type httpRecorder struct{}
+
+func (httpRecorder) GetMiners() ([]sapi.Miner, error) {
+	return server.GetMiners()
+}
+
+func (httpRecorder) GetCheckpoint(name string) (*sapi.Checkpoint, error) {
+	return server.GetCheckpoint(name)
+}
+
+func (httpRecorder) UpdatePowerInfo(power *sapi.PowerInfo) error {
+	return server.UpdatePowerInfo(power)
+}
+
+func (httpRecorder) UpdatePeerInfo(peer *sapi.PeerInfo) error {
+	return server.UpdatePeerInfo(peer)
+}
+
+func (httpRecorder) UpdateMetaInfo(meta *sapi.MinerMeta) error {
+	return server.UpdateMetaInfo(meta)
+}
+
+func (httpRecorder) UpdateSectorPower(sectors *sapi.SectorPower) error {
+	return server.UpdateSectorPower(sectors)
+}
+
+func (httpRecorder) UpdateFaultInfo(faults *sapi.FaultInfo) error {
+	return server.UpdateFaultInfo(faults)
+}
+
+func (httpRecorder) UpdateBalanceInfo(balance *sapi.BalanceInfo) error {
+	return server.UpdateBalanceInfo(balance)
+}
+
+func (httpRecorder) UpdateExpirations(exps []sapi.SectorExpiration) error {
+	return server.UpdateExpirations(exps)
+}
+
+func (httpRecorder) UpdateBlocks(blocks []sapi.BlockInfo) error {
+	return server.UpdateBlocks(blocks)
+}
+
+func (httpRecorder) UpdateAgentInfo(agent *sapi.AgentInfo) error {
+	return server.UpdateAgentInfo(agent)
+}
+
+func (httpRecorder) UpdateIdentifyInfo(identify *sapi.IdentifyInfo) error {
+	return server.UpdateIdentifyInfo(identify)
+}
+
+func (httpRecorder) UpdateProbeResult(probe *sapi.ProbeResult) error {
+	return server.UpdateProbeResult(probe)
+}
+
+func (httpRecorder) UpdateAddrProbes(probes []sapi.AddrProbe) error {
+	return server.UpdateAddrProbes(probes)
+}
+
+func (httpRecorder) UpdateGeoInfo(geo *sapi.GeoInfo) error {
+	return server.UpdateGeoInfo(geo)
+}
+
+func (httpRecorder) UpdateCheckpoint(cp *sapi.Checkpoint) error {
+	return server.UpdateCheckpoint(cp)
+}
+
+func (httpRecorder) RecordFailure(f *failure) error {
+	return nil
+}
+
+// Transaction is not supported over http, records are posted as fn goes
+func (r httpRecorder) Transaction(fn func(r recorder) error) error {
+	return fn(r)
+}
+
+// dbRecorder write records through the same Api methods as the http handlers
+type dbRecorder struct {
+	api *sapi.Api
+}
+
+func (r *dbRecorder) GetMiners() ([]sapi.Miner, error) {
+	return r.api.GetAllMiners()
+}
+
+func (r *dbRecorder) GetCheckpoint(name string) (*sapi.Checkpoint, error) {
+	return r.api.GetCheckpoint(name)
+}
+
+func (r *dbRecorder) UpdatePowerInfo(power *sapi.PowerInfo) error {
+	return r.api.UpdateMinerPowerInfo(power)
+}
+
+func (r *dbRecorder) UpdatePeerInfo(peer *sapi.PeerInfo) error {
+	return r.api.UpdateMinerPeerInfo(peer)
+}
+
+func (r *dbRecorder) UpdateMetaInfo(meta *sapi.MinerMeta) error {
+	return r.api.UpdateMinerMeta(meta)
+}
+
+func (r *dbRecorder) UpdateSectorPower(sectors *sapi.SectorPower) error {
+	return r.api.UpdateMinerSectorPower(sectors)
+}
+
+func (r *dbRecorder) UpdateFaultInfo(faults *sapi.FaultInfo) error {
+	return r.api.UpdateMinerFaultInfo(faults)
+}
+
+func (r *dbRecorder) UpdateBalanceInfo(balance *sapi.BalanceInfo) error {
+	return r.api.UpdateMinerBalance(balance)
+}
+
+func (r *dbRecorder) UpdateExpirations(exps []sapi.SectorExpiration) error {
+	return r.api.UpdateMinerExpirations(exps)
+}
+
+func (r *dbRecorder) UpdateBlocks(blocks []sapi.BlockInfo) error {
+	return r.api.UpdateBlocks(blocks)
+}
+
+func (r *dbRecorder) UpdateAgentInfo(agent *sapi.AgentInfo) error {
+	return r.api.UpdateMinerAgentInfo(agent)
+}
+
+func (r *dbRecorder) UpdateIdentifyInfo(identify *sapi.IdentifyInfo) error {
+	return r.api.UpdateMinerIdentifyInfo(identify)
+}
+
+func (r *dbRecorder) UpdateProbeResult(probe *sapi.ProbeResult) error {
+	return r.api.UpdateMinerProbeResult(probe)
+}
+
+func (r *dbRecorder) UpdateAddrProbes(probes []sapi.AddrProbe) error {
+	return r.api.UpdateMinerAddrProbes(probes)
+}
+
+func (r *dbRecorder) UpdateGeoInfo(geo *sapi.GeoInfo) error {
+	return r.api.UpdateMinerGeoInfo(geo)
+}
+
+func (r *dbRecorder) UpdateCheckpoint(cp *sapi.Checkpoint) error {
+	return r.api.UpdateCheckpoint(cp)
+}
+
+func (r *dbRecorder) RecordFailure(f *failure) error {
+	return nil
+}
+
+func (r *dbRecorder) Transaction(fn func(r recorder) error) error {
+	return r.api.Transaction(func(tx *sapi.Api) error {
+		return fn(&dbRecorder{api: tx})
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	sapi "static-power/api"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/actors/builtin/miner"
+)
+
+// sumSectors sum the deal weights of active sectors of a miner, to get how many bytes are
+// covered by verified deals, regular deals or nothing, instead of inferring from QAP
+func sumSectors(aid abi.ActorID, size abi.SectorSize, sectors []*miner.SectorOnChainInfo) *sapi.SectorPower {
+	cc := big.Zero()
+	deal := big.Zero()
+	verified := big.Zero()
+	for _, s := range sectors {
+		total := big.NewIntUnsigned(uint64(size))
+
+		// deal weights are integral of deal space over the sector lifetime
+		duration := big.NewInt(int64(s.Expiration - s.Activation))
+		if duration.LessThanEqual(big.Zero()) {
+			cc = big.Add(cc, total)
+			continue
+		}
+		d := big.Div(s.DealWeight, duration)
+		v := big.Div(s.VerifiedDealWeight, duration)
+
+		deal = big.Add(deal, d)
+		verified = big.Add(verified, v)
+		cc = big.Add(cc, big.Max(big.Sub(total, big.Add(d, v)), big.Zero()))
+	}
+
+	ccp := sapi.Power(cc)
+	dp := sapi.Power(deal)
+	vp := sapi.Power(verified)
+	return &sapi.SectorPower{
+		MinerID:       aid,
+		Sectors:       uint64(len(sectors)),
+		CCBytes:       &ccp,
+		DealBytes:     &dp,
+		VerifiedBytes: &vp,
+	}
+}
@@ -6,18 +6,129 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"static-power/api"
+	"strconv"
 )
 
 var client *http.Client = &http.Client{}
 var host string = "127.0.0.1:8090"
 
+// network of records to read and write, the server takes mainnet if empty
+var network string
+
 func SetHost(h string) {
 	host = h
 }
 
+func SetNetwork(n string) {
+	network = n
+}
+
+// how statistics select miners below min power, the server excludes them if empty
+var belowMin string
+
+func SetBelowMin(sel string) {
+	belowMin = sel
+}
+
 func baseUrl(rel string) string {
-	return "http://" + host + "/api/v0/" + rel
+	return queryUrl(rel, url.Values{})
+}
+
+// queryUrl is baseUrl with query parameters q
+func queryUrl(rel string, q url.Values) string {
+	u := "http://" + host + "/api/v0/" + rel
+	if network != "" {
+		q.Set("network", network)
+	}
+	if belowMin != "" {
+		q.Set("below-min", belowMin)
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
+}
+
+// getJSON decode the response of GET u into v, errors of the server are returned as is
+func getJSON(u string, rel string, v interface{}) error {
+	resp, err := client.Get(u)
+	if err != nil {
+		return fmt.Errorf("get /%s err: %w", rel, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+			return fmt.Errorf("get /%s: %s", rel, body.Error)
+		}
+		return fmt.Errorf("get /%s status: %s", rel, resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return fmt.Errorf("decode error: %w", err)
+	}
+	return nil
+}
+
+// GetMinersByImpl get miners of implementation impl, all miners if empty
+func GetMinersByImpl(impl string) ([]api.Miner, error) {
+	var miners []api.Miner
+	err := getJSON(queryUrl("miner", url.Values{"impl": {impl}}), "miner", &miners)
+	return miners, err
+}
+
+// GetMinerDetail get miner by address, api.ErrMinerNotFound if the daemon has no record of it
+func GetMinerDetail(addr string) (*api.MinerDetail, error) {
+	rel := "miner/" + url.PathEscape(addr)
+	resp, err := client.Get(baseUrl(rel))
+	if err != nil {
+		return nil, fmt.Errorf("get /miner err: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, api.ErrMinerNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get /%s status: %s", rel, resp.Status)
+	}
+	var detail api.MinerDetail
+	err = json.NewDecoder(resp.Body).Decode(&detail)
+	if err != nil {
+		return nil, fmt.Errorf("decode error: %w", err)
+	}
+	return &detail, nil
+}
+
+func GetProportion() (float64, error) {
+	var body struct {
+		Proportion float64 `json:"proportion"`
+	}
+	err := getJSON(baseUrl("proportion"), "proportion", &body)
+	return body.Proportion, err
+}
+
+// GetStatic get the stats of implementation impl, venus or lotus
+func GetStatic(impl string) (*api.StaticInfo, error) {
+	var s api.StaticInfo
+	err := getJSON(baseUrl("static/"+impl), "static/"+impl, &s)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetExpirationStatic get the power of implementations expiring in the next months
+func GetExpirationStatic(months int) ([]*api.ExpirationStaticInfo, error) {
+	var s []*api.ExpirationStaticInfo
+	err := getJSON(queryUrl("static/expirations", url.Values{"months": {strconv.Itoa(months)}}), "static/expirations", &s)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 func GetMiners() ([]api.Miner, error) {
@@ -81,3 +192,192 @@ func UpdatePeerInfo(peer *api.PeerInfo) error {
 	defer resp.Body.Close()
 	return nil
 }
+
+func UpdateIdentifyInfo(identify *api.IdentifyInfo) error {
+	data, err := json.Marshal(identify)
+	if err != nil {
+		return fmt.Errorf("marshal identify info error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("identify"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /identify err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func UpdateProbeResult(probe *api.ProbeResult) error {
+	data, err := json.Marshal(probe)
+	if err != nil {
+		return fmt.Errorf("marshal probe result error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("probe"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /probe err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func UpdateAddrProbes(probes []api.AddrProbe) error {
+	data, err := json.Marshal(probes)
+	if err != nil {
+		return fmt.Errorf("marshal addr probes error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("addrs"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /addrs err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func UpdateGeoInfo(geo *api.GeoInfo) error {
+	data, err := json.Marshal(geo)
+	if err != nil {
+		return fmt.Errorf("marshal geo info error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("geo"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /geo err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func UpdateMetaInfo(meta *api.MinerMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("marshal miner meta error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("meta"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /meta err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func UpdateSectorPower(sectors *api.SectorPower) error {
+	data, err := json.Marshal(sectors)
+	if err != nil {
+		return fmt.Errorf("marshal sector power error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("sectors"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /sectors err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func UpdateFaultInfo(faults *api.FaultInfo) error {
+	data, err := json.Marshal(faults)
+	if err != nil {
+		return fmt.Errorf("marshal fault info error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("faults"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /faults err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func UpdateBalanceInfo(balance *api.BalanceInfo) error {
+	data, err := json.Marshal(balance)
+	if err != nil {
+		return fmt.Errorf("marshal balance info error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("balance"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /balance err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func UpdateBlocks(blocks []api.BlockInfo) error {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return fmt.Errorf("marshal blocks error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("blocks"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /blocks err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func UpdateExpirations(exps []api.SectorExpiration) error {
+	data, err := json.Marshal(exps)
+	if err != nil {
+		return fmt.Errorf("marshal expirations error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("expirations"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /expirations err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	return nil
+}
+
+func GetCheckpoint(name string) (*api.Checkpoint, error) {
+	resp, err := client.Get(baseUrl("checkpoint/" + name))
+	if err != nil {
+		return nil, fmt.Errorf("get /checkpoint err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, api.ErrCheckpointNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get /checkpoint status: %s", resp.Status)
+	}
+	var cp api.Checkpoint
+	err = json.NewDecoder(resp.Body).Decode(&cp)
+	if err != nil {
+		return nil, fmt.Errorf("decode error: %w", err)
+	}
+	return &cp, nil
+}
+
+func UpdateCheckpoint(cp *api.Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("marshal checkpoint error: %w", err)
+	}
+	r := bytes.NewReader(data)
+	resp, err := client.Post(baseUrl("checkpoint"), "application/json", r)
+	if err != nil {
+		return fmt.Errorf("post /checkpoint err: %w", err)
+	}
+	log.Println(resp.Status)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("post /checkpoint status: %s", resp.Status)
+	}
+	return nil
+}
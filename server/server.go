@@ -3,18 +3,23 @@ package server
 import (
 	"bytes"
 	"encoding/csv"
+	"errors"
+	"fmt"
 	"net/http"
 	"static-power/api"
 	"strconv"
 	"strings"
 
+	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/gin-gonic/gin"
 )
 
-var srv *gin.Engine = gin.Default()
+// srv is created on RegisterApi, so commands only using the client print nothing of gin
+var srv *gin.Engine
 
 func RegisterApi(a *api.Api) {
-	srv.Use(CORSMiddleware())
+	srv = gin.Default()
+	srv.Use(CORSMiddleware(), checkQuery())
 
 	srv.GET("/api/v0/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -23,15 +28,40 @@ func RegisterApi(a *api.Api) {
 	})
 
 	srv.GET("/api/v0/miner", func(c *gin.Context) {
-		miners, err := a.GetAllMiners()
+		// impl select miners of one implementation, venus, lotus, others or unknown
+		impl, err := api.ParseImpl(c.Query("impl"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		miners, err := forNetwork(a, c).GetMinersByImpl(impl)
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
+			return
 		}
 		c.JSON(200, miners)
 	})
 
+	srv.GET("/api/v0/miner/:addr", func(c *gin.Context) {
+		id, err := api.ParseMinerID(c.Param("addr"))
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		miner, err := forNetwork(a, c).GetMinerDetail(id)
+		if errors.Is(err, api.ErrMinerNotFound) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, miner)
+	})
+
 	srv.GET("/api/v0/proportion", func(c *gin.Context) {
-		p, err := a.GetProportion()
+		p, err := forNetwork(a, c).GetProportion()
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 		}
@@ -39,7 +69,7 @@ func RegisterApi(a *api.Api) {
 	})
 
 	srv.GET("/api/v0/static/venus", func(c *gin.Context) {
-		s, err := a.GetVenusStatic()
+		s, err := forNetwork(a, c).GetVenusStatic()
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 		}
@@ -47,22 +77,165 @@ func RegisterApi(a *api.Api) {
 	})
 
 	srv.GET("/api/v0/static/lotus", func(c *gin.Context) {
-		s, err := a.GetLotusStatic()
+		s, err := forNetwork(a, c).GetLotusStatic()
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 		}
 		c.JSON(200, s)
 	})
 
+	srv.GET("/api/v0/static/protocols", func(c *gin.Context) {
+		s, err := forNetwork(a, c).GetProtocolStatic()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/static/reachability", func(c *gin.Context) {
+		s, err := forNetwork(a, c).GetReachabilityStatic()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/static/addrs", func(c *gin.Context) {
+		s, err := forNetwork(a, c).GetAddrStatic()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/static/geo", func(c *gin.Context) {
+		s, err := forNetwork(a, c).GetGeoStatic()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/static/versions", func(c *gin.Context) {
+		s, err := forNetwork(a, c).GetVersionStatic()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/static/meta", func(c *gin.Context) {
+		s, err := forNetwork(a, c).GetMetaStatic()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/static/faults", func(c *gin.Context) {
+		// days of history to compare, 30 by default
+		days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+		if err != nil || days <= 0 {
+			c.JSON(400, gin.H{"error": "days should be a positive number"})
+			return
+		}
+		s, err := forNetwork(a, c).GetFaultStatic(days)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/static/balance", func(c *gin.Context) {
+		s, err := forNetwork(a, c).GetBalanceStatic()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/static/blocks", func(c *gin.Context) {
+		// epoch range of blocks, the last day of blocks recorded by default
+		var epochs [2]abi.ChainEpoch
+		for i, name := range []string{"from", "to"} {
+			v, err := strconv.ParseInt(c.DefaultQuery(name, "0"), 10, 64)
+			if err != nil {
+				c.JSON(400, gin.H{"error": fmt.Sprintf("parse %s: %s", name, err)})
+				return
+			}
+			epochs[i] = abi.ChainEpoch(v)
+		}
+		s, err := forNetwork(a, c).GetBlockStatic(epochs[0], epochs[1])
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/static/expirations", func(c *gin.Context) {
+		// months to forecast, 12 by default
+		months, err := strconv.Atoi(c.DefaultQuery("months", "12"))
+		if err != nil || months <= 0 {
+			c.JSON(400, gin.H{"error": "months should be a positive number"})
+			return
+		}
+		s, err := forNetwork(a, c).GetExpirationStatic(months)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/entities", func(c *gin.Context) {
+		s, err := forNetwork(a, c).GetEntities()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/clusters", func(c *gin.Context) {
+		s, err := forNetwork(a, c).GetClusters()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, s)
+	})
+
+	srv.GET("/api/v0/checkpoint/:name", func(c *gin.Context) {
+		cp, err := forNetwork(a, c).GetCheckpoint(c.Param("name"))
+		if errors.Is(err, api.ErrCheckpointNotFound) {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, cp)
+	})
+
 	srv.GET("/api/v0/miners/csv", func(c *gin.Context) {
-		miners, err := a.GetAllMiners()
+		miners, err := forNetwork(a, c).GetAllMiners()
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 		}
 		// transform to csv
 		buf := bytes.NewBuffer([]byte{})
 		w := csv.NewWriter(buf)
-		w.Write([]string{"miner_id", "peer_id", "multiaddrs", "agent_name", "raw_byte_power", "quality_adj_power"})
+		w.Write([]string{"miner_id", "peer_id", "multiaddrs", "agent_name", "raw_byte_power", "quality_adj_power", "owner", "worker", "beneficiary", "sector_size", "window_post_proof_type"})
 		for _, miner := range miners {
 			minerID := strconv.Itoa(int(miner.ID))
 			peerId := ""
@@ -70,6 +243,11 @@ func RegisterApi(a *api.Api) {
 			agentName := ""
 			rawBytePower := ""
 			qualityAdjPower := ""
+			owner := ""
+			worker := ""
+			beneficiary := ""
+			sectorSize := ""
+			windowPoStProofType := ""
 
 			if miner.Peer != nil {
 				peerId = miner.Peer.PeerId
@@ -87,6 +265,14 @@ func RegisterApi(a *api.Api) {
 				qualityAdjPower = miner.Power.QualityAdjPower.String()
 			}
 
+			if miner.Meta != nil {
+				owner = miner.Meta.Owner
+				worker = miner.Meta.Worker
+				beneficiary = miner.Meta.Beneficiary
+				sectorSize = miner.Meta.SectorSize.ShortString()
+				windowPoStProofType = strconv.Itoa(int(miner.Meta.WindowPoStProofType))
+			}
+
 			w.Write([]string{
 				minerID,
 				peerId,
@@ -94,6 +280,11 @@ func RegisterApi(a *api.Api) {
 				agentName,
 				rawBytePower,
 				qualityAdjPower,
+				owner,
+				worker,
+				beneficiary,
+				sectorSize,
+				windowPoStProofType,
 			})
 		}
 		w.Flush()
@@ -108,7 +299,7 @@ func RegisterApi(a *api.Api) {
 		// get data from body by json
 		var peer api.PeerInfo
 		c.Bind(&peer)
-		err := a.UpdateMinerPeerInfo(&peer)
+		err := forNetwork(a, c).UpdateMinerPeerInfo(&peer)
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
@@ -119,7 +310,128 @@ func RegisterApi(a *api.Api) {
 	srv.POST("/api/v0/agent", func(c *gin.Context) {
 		var agent api.AgentInfo
 		c.Bind(&agent)
-		err := a.UpdateMinerAgentInfo(&agent)
+		err := forNetwork(a, c).UpdateMinerAgentInfo(&agent)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/identify", func(c *gin.Context) {
+		var identify api.IdentifyInfo
+		c.Bind(&identify)
+		err := forNetwork(a, c).UpdateMinerIdentifyInfo(&identify)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/probe", func(c *gin.Context) {
+		var probe api.ProbeResult
+		c.Bind(&probe)
+		err := forNetwork(a, c).UpdateMinerProbeResult(&probe)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/addrs", func(c *gin.Context) {
+		var probes []api.AddrProbe
+		c.Bind(&probes)
+		err := forNetwork(a, c).UpdateMinerAddrProbes(probes)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/geo", func(c *gin.Context) {
+		var geo api.GeoInfo
+		c.Bind(&geo)
+		err := forNetwork(a, c).UpdateMinerGeoInfo(&geo)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/meta", func(c *gin.Context) {
+		var meta api.MinerMeta
+		c.Bind(&meta)
+		err := forNetwork(a, c).UpdateMinerMeta(&meta)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/sectors", func(c *gin.Context) {
+		var sectors api.SectorPower
+		c.Bind(&sectors)
+		err := forNetwork(a, c).UpdateMinerSectorPower(&sectors)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/balance", func(c *gin.Context) {
+		var balance api.BalanceInfo
+		c.Bind(&balance)
+		err := forNetwork(a, c).UpdateMinerBalance(&balance)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/blocks", func(c *gin.Context) {
+		var blocks []api.BlockInfo
+		c.Bind(&blocks)
+		err := forNetwork(a, c).UpdateBlocks(blocks)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/expirations", func(c *gin.Context) {
+		var exps []api.SectorExpiration
+		c.Bind(&exps)
+		err := forNetwork(a, c).UpdateMinerExpirations(exps)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/faults", func(c *gin.Context) {
+		var faults api.FaultInfo
+		c.Bind(&faults)
+		err := forNetwork(a, c).UpdateMinerFaultInfo(&faults)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"message": "ok"})
+	})
+
+	srv.POST("/api/v0/checkpoint", func(c *gin.Context) {
+		var cp api.Checkpoint
+		c.Bind(&cp)
+		err := forNetwork(a, c).UpdateCheckpoint(&cp)
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
@@ -130,7 +442,7 @@ func RegisterApi(a *api.Api) {
 	srv.POST("/api/v0/power", func(c *gin.Context) {
 		var power api.PowerInfo
 		c.Bind(&power)
-		err := a.UpdateMinerPowerInfo(&power)
+		err := forNetwork(a, c).UpdateMinerPowerInfo(&power)
 		if err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
@@ -160,3 +472,21 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// forNetwork return the Api of the network selected by query parameter network, mainnet by default,
+// records posted without network are stored into it, statistics select miners below min power
+// by query parameter below-min, see api.BelowMinExclude
+func forNetwork(a *api.Api, c *gin.Context) *api.Api {
+	return a.ForNetwork(c.Query("network")).WithBelowMin(c.Query("below-min"))
+}
+
+// checkQuery reject requests with query parameters forNetwork doesn't understand
+func checkQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := api.ParseBelowMin(c.Query("below-min")); err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.Next()
+	}
+}
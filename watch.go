@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/urfave/cli/v2"
+)
+
+// name of the checkpoint kept by watch
+const watchCheckpoint = "watch"
+
+// types of api.HeadChange, same as those in lotus chain/store
+const (
+	hcRevert  = "revert"
+	hcApply   = "apply"
+	hcCurrent = "current"
+)
+
+var watchCmd = &cli.Command{
+	Name:  "watch",
+	Usage: "follow the chain head and update power of miners changed in every tipset",
+	Flags: append(append(nodeFlags, recorderFlags...),
+		&cli.BoolFlag{
+			Name:  "fast",
+			Usage: "read power of all miners from the power actor state in one pass, fall back to query miners one by one if failed",
+			Value: true,
+		},
+		&cli.BoolFlag{
+			Name:  "deep",
+			Usage: "read active sectors of changed miners to measure verified and regular deal power",
+		},
+		&cli.BoolFlag{
+			Name:  "expirations",
+			Usage: "read active sectors of changed miners to schedule the power expiring in each month",
+		},
+		&cli.BoolFlag{
+			Name:  "include-below-min",
+			Usage: "also crawl miners with power below the min power, which are flagged BelowMin",
+		},
+	),
+	Action: func(c *cli.Context) error {
+		node, closer, err := connectNode(c)
+		if err != nil {
+			return err
+		}
+		defer closer()
+
+		r, err := openNodeRecorder(c, node)
+		if err != nil {
+			return err
+		}
+
+		return watchHead(c.Context, node, r, crawlOptions{
+			fast:            c.Bool("fast"),
+			deep:            c.Bool("deep"),
+			expirations:     c.Bool("expirations"),
+			includeBelowMin: c.Bool("include-below-min"),
+		})
+	},
+}
+
+// watchHead crawl miners changed between the last crawled tipset and every new head,
+// as the diff is taken between state roots, changes in reverted tipsets are undone as well
+func watchHead(ctx context.Context, node ChainNode, r recorder, opts crawlOptions) error {
+	notifs, err := node.ChainNotify(ctx)
+	if err != nil {
+		return fmt.Errorf("subscribe chain notify: %w", err)
+	}
+
+	var last *types.TipSet
+	for {
+		var changes []*api.HeadChange
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case hc, ok := <-notifs:
+			if !ok {
+				return fmt.Errorf("chain notify channel closed")
+			}
+			changes = hc
+		}
+		// catch up with notifications queued during the last crawl, only the latest head is crawled
+	drain:
+		for {
+			select {
+			case hc, ok := <-notifs:
+				if !ok {
+					return fmt.Errorf("chain notify channel closed")
+				}
+				changes = append(changes, hc...)
+			default:
+				break drain
+			}
+		}
+
+		head := latestHead(changes)
+		if head == nil {
+			continue
+		}
+		if last != nil && head.ParentState() == last.ParentState() {
+			continue
+		}
+
+		crawlOpts := opts
+		if last == nil {
+			crawlOpts.only, err = changedSinceCheckpoint(ctx, node, r, watchCheckpoint, head)
+		} else {
+			crawlOpts.only, err = changedActors(ctx, node, last.ParentState(), head.ParentState())
+		}
+		if err != nil {
+			log.Printf("crawl all miners at %d: %s", head.Height(), err)
+		}
+
+		// the diff starts from last again if any miner failed, so they are crawled again with the next head
+		complete, err := crawlAndCheckpoint(ctx, node, r, watchCheckpoint, head, crawlOpts)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if complete {
+			last = head
+		}
+	}
+}
+
+// latestHead return the head after applying changes in order, nil if the head is reverted only
+func latestHead(changes []*api.HeadChange) *types.TipSet {
+	var head *types.TipSet
+	for _, change := range changes {
+		switch change.Type {
+		case hcCurrent, hcApply:
+			head = change.Val
+		case hcRevert:
+			// the parent of a reverted tipset is applied again by the following changes
+			head = nil
+		}
+	}
+	return head
+}